@@ -0,0 +1,116 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const defaultBatchConcurrency = 8
+
+type (
+	// RequestSpec describes one request of a batch.
+	RequestSpec struct {
+		Method  string
+		URL     string
+		Body    []byte
+		Headers map[string]string
+	}
+
+	// BatchResult holds the outcome of the RequestSpec at the same index.
+	BatchResult struct {
+		Body []byte
+		Err  error
+	}
+
+	BatchOptions struct {
+		// Concurrency bounds the number of requests in flight. Defaults to 8.
+		Concurrency int
+		// FailFast cancels the pending requests as soon as one fails.
+		// Otherwise every request runs and all errors are collected.
+		FailFast bool
+	}
+)
+
+// DoBatch executes specs with bounded concurrency. Results are returned in
+// the order of specs. The returned error is the first failure when FailFast
+// is set, or every failure joined otherwise.
+func (c *Client) DoBatch(ctx context.Context, specs []RequestSpec, opts BatchOptions) ([]BatchResult, error) {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		results  = make([]BatchResult, len(specs))
+		sem      = make(chan struct{}, concurrency)
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+
+	for i, spec := range specs {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+			if opts.FailFast {
+				once.Do(func() {
+					firstErr = fmt.Errorf("request %d: %w", i, ctx.Err())
+				})
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, spec RequestSpec) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			body, err := c.doSpec(ctx, spec)
+			results[i] = BatchResult{Body: body, Err: err}
+
+			if err != nil && opts.FailFast {
+				once.Do(func() {
+					firstErr = fmt.Errorf("request %d: %w", i, err)
+					cancel()
+				})
+			}
+		}(i, spec)
+	}
+
+	wg.Wait()
+
+	if opts.FailFast {
+		return results, firstErr
+	}
+
+	var errs []error
+	for i, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("request %d: %w", i, r.Err))
+		}
+	}
+
+	return results, errors.Join(errs...)
+}
+
+func (c *Client) doSpec(ctx context.Context, spec RequestSpec) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r := c.R(ctx).Method(spec.Method).URL(spec.URL).Headers(spec.Headers)
+	if spec.Body != nil {
+		r.Body(bytes.NewReader(spec.Body))
+	}
+
+	return r.Do()
+}
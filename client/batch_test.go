@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestThatBatchReturnsResultsInOrderAndBoundsConcurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+
+		time.Sleep(5 * time.Millisecond)
+		w.Write([]byte(r.URL.Query().Get("id")))
+	}))
+	defer server.Close()
+
+	specs := make([]RequestSpec, 20)
+	for i := range specs {
+		specs[i] = RequestSpec{Method: "GET", URL: fmt.Sprintf("%s?id=%d", server.URL, i)}
+	}
+
+	cl := NewClient(server.Client())
+	results, err := cl.DoBatch(context.Background(), specs, BatchOptions{Concurrency: 3})
+
+	assert.NoError(t, err)
+	for i, r := range results {
+		assert.NoError(t, r.Err)
+		assert.Equal(t, fmt.Sprint(i), string(r.Body))
+	}
+	assert.LessOrEqual(t, maxInFlight, int32(3))
+}
+
+func TestThatBatchCollectsAllErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("fail") != "" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	specs := []RequestSpec{
+		{Method: "GET", URL: server.URL + "?fail=1"},
+		{Method: "GET", URL: server.URL},
+		{Method: "GET", URL: server.URL + "?fail=1"},
+	}
+
+	results, err := NewClient(server.Client()).DoBatch(context.Background(), specs, BatchOptions{})
+
+	assert.Error(t, err)
+	assert.Error(t, results[0].Err)
+	assert.NoError(t, results[1].Err)
+	assert.Error(t, results[2].Err)
+}
+
+func TestThatBatchFailFastCancelsPendingRequests(t *testing.T) {
+	var calls int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	specs := make([]RequestSpec, 50)
+	for i := range specs {
+		specs[i] = RequestSpec{Method: "GET", URL: server.URL}
+	}
+
+	results, err := NewClient(server.Client()).DoBatch(context.Background(), specs, BatchOptions{Concurrency: 1, FailFast: true})
+
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.ErrorIs(t, results[len(results)-1].Err, context.Canceled)
+}
+
+func TestThatBatchFailFastFailsWhenTheContextIsAlreadyCanceled(t *testing.T) {
+	var calls int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer server.Close()
+
+	specs := make([]RequestSpec, 20)
+	for i := range specs {
+		specs[i] = RequestSpec{Method: "GET", URL: server.URL}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, err := NewClient(server.Client()).DoBatch(ctx, specs, BatchOptions{Concurrency: 1, FailFast: true})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+	for _, r := range results {
+		assert.ErrorIs(t, r.Err, context.Canceled)
+	}
+}
+
+func TestThatBatchPropagatesTraceFromContext(t *testing.T) {
+	tid, _ := trace.TraceIDFromHex("0af7651916cd43dd8448eb211c80319c")
+	sid, _ := trace.SpanIDFromHex("b7ad6b7169203331")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    tid,
+		SpanID:     sid,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get(traceparent)))
+	}))
+	defer server.Close()
+
+	results, err := NewClient(server.Client()).DoBatch(ctx, []RequestSpec{{Method: "GET", URL: server.URL}}, BatchOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", string(results[0].Body))
+}
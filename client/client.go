@@ -9,6 +9,9 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 const traceparent = "traceparent"
@@ -17,6 +20,9 @@ type (
 	Client struct {
 		client             HTTPClient
 		authorizationToken string
+		recorder           *HARRecorder
+		codecsMu           sync.RWMutex
+		codecs             []codec
 	}
 
 	HTTPClient interface {
@@ -37,12 +43,7 @@ func (c *Client) DoRequest(
 	method, url string,
 	body io.Reader,
 ) ([]byte, error) {
-	request, err := c.setGenericHeaders(method, url, body, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	return c.execute(ctx, request)
+	return c.R(ctx).detach().Method(method).URL(url).Body(body).Do()
 }
 
 func (c *Client) DoRequestWithExtraHeaders(
@@ -50,12 +51,7 @@ func (c *Client) DoRequestWithExtraHeaders(
 	method, url string,
 	body io.Reader,
 	extraHeaders map[string]string) ([]byte, error) {
-	request, err := c.setGenericHeaders(method, url, body, extraHeaders)
-	if err != nil {
-		return nil, err
-	}
-
-	return c.execute(ctx, request)
+	return c.R(ctx).detach().Method(method).URL(url).Body(body).Headers(extraHeaders).Do()
 }
 
 func SetAuthorizationHeader(request *http.Request, token string) {
@@ -80,17 +76,28 @@ func (c *Client) buildRequest(
 	return req, nil
 }
 
-func (c *Client) execute(_ context.Context, req *http.Request) ([]byte, error) {
+func (c *Client) execute(ctx context.Context, req *http.Request) ([]byte, error) {
+	_, bodyBytes, err := c.do(ctx, req)
+
+	return bodyBytes, err
+}
+
+// do sends req and reads the whole response body, failing on non 2xx status.
+func (c *Client) do(ctx context.Context, req *http.Request) (*http.Response, []byte, error) {
+	req, rec := c.startRecording(ctx, req)
+
 	res, err := c.client.Do(req)
 	if err != nil {
-		return nil, err
+		rec.finish(nil, nil, err)
+		return nil, nil, err
 	}
 
 	defer res.Body.Close()
 
 	bodyBytes, err := io.ReadAll(res.Body)
+	rec.finish(res, bodyBytes, err)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
 		var err error
@@ -101,10 +108,10 @@ func (c *Client) execute(_ context.Context, req *http.Request) ([]byte, error) {
 			err = fmt.Errorf("status %d", res.StatusCode)
 		}
 
-		return nil, err
+		return nil, nil, err
 	}
 
-	return bodyBytes, nil
+	return res, bodyBytes, nil
 }
 
 func (c *Client) BuildUrl(baseURL string, params map[string]string) string {
@@ -120,16 +127,7 @@ func (c *Client) BuildUrl(baseURL string, params map[string]string) string {
 }
 
 func (c *Client) DoRequestWithContentType(ctx context.Context, method, url string, body io.Reader, contentType string) ([]byte, error) {
-	request, err := c.setGenericHeaders(method, url, body, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	if contentType != "" {
-		request.Header.Set("Content-Type", contentType)
-	}
-
-	return c.execute(ctx, request)
+	return c.R(ctx).detach().Method(method).URL(url).Body(body).ContentType(contentType).Do()
 }
 
 func (c *Client) setGenericHeaders(method string, url string, body io.Reader, extraHeaders map[string]string) (*http.Request, error) {
@@ -162,6 +160,19 @@ func SetTraceparentHeader(request *http.Request) {
 	request.Header.Set(traceparent, GetTraceparent())
 }
 
+// SetTraceparentFromContext propagates the span found in ctx, if any,
+// instead of the process wide traceparent.
+func SetTraceparentFromContext(ctx context.Context, request *http.Request) {
+	s := trace.SpanContextFromContext(ctx)
+	if !s.IsValid() {
+		return
+	}
+
+	value := fmt.Sprintf("00-%s-%s-%s", s.TraceID(), s.SpanID(), s.TraceFlags())
+	request.Header.Set("proper-referer", value)
+	request.Header.Set(traceparent, value)
+}
+
 func SetFlowID(request *http.Request) {
 	request.Header.Set("X-Flow-Id", GetFlowID())
 }
@@ -234,10 +245,18 @@ func (c *Client) BuildMultipartFormRequest(
 }
 
 func (c *Client) DoRequestRaw(ctx context.Context, req *http.Request) (*http.Response, error) {
+	req, rec := c.startRecording(ctx, req)
+
 	res, err := c.client.Do(req)
 	if err != nil {
+		rec.finish(res, nil, err)
 		return res, err
 	}
 
+	if rec != nil {
+		// The caller owns the body, which is recorded as it's read.
+		rec.recordBody(res)
+	}
+
 	return res, nil
 }
@@ -0,0 +1,331 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	ContentTypeJSON = "application/json"
+	ContentTypeXML  = "application/xml"
+	ContentTypeCSV  = "text/csv"
+	ContentTypeForm = "application/x-www-form-urlencoded"
+)
+
+var ErrNoCodec = errors.New("no codec registered for content type")
+
+type (
+	// Codec decodes a response body into v.
+	Codec interface {
+		Decode(data []byte, v interface{}) error
+	}
+
+	// CodecFunc adapts a function to the Codec interface.
+	CodecFunc func(data []byte, v interface{}) error
+
+	// CSVCodec decodes CSV with a header row into a pointer to a slice of
+	// structs, matching columns with the `csv` tag of each field, or into
+	// *[][]string.
+	CSVCodec struct {
+		Comma rune
+	}
+
+	// FormCodec decodes form-urlencoded bodies into *url.Values,
+	// *map[string]string or a struct pointer using the `form` tag.
+	FormCodec struct{}
+
+	codec struct {
+		contentType string
+		codec       Codec
+	}
+)
+
+func defaultCodecs() []codec {
+	return []codec{
+		{contentType: ContentTypeJSON, codec: CodecFunc(json.Unmarshal)},
+		{contentType: ContentTypeXML, codec: CodecFunc(xml.Unmarshal)},
+		{contentType: "text/xml", codec: CodecFunc(xml.Unmarshal)},
+		{contentType: ContentTypeCSV, codec: CSVCodec{Comma: ','}},
+		{contentType: ContentTypeForm, codec: FormCodec{}},
+	}
+}
+
+func (f CodecFunc) Decode(data []byte, v interface{}) error {
+	return f(data, v)
+}
+
+// RegisterCodec adds or replaces the codec used for contentType. It's safe
+// to call while the client is in use.
+func (c *Client) RegisterCodec(contentType string, cd Codec) {
+	c.codecsMu.Lock()
+	defer c.codecsMu.Unlock()
+
+	codecs := c.codecs
+	if codecs == nil {
+		codecs = defaultCodecs()
+	}
+
+	// Copied, so the lists handed to the requests in flight never change.
+	contentType = strings.ToLower(contentType)
+	registered := append([]codec{}, codecs...)
+	for i := range registered {
+		if registered[i].contentType == contentType {
+			registered[i].codec = cd
+			c.codecs = registered
+			return
+		}
+	}
+
+	c.codecs = append(registered, codec{contentType: contentType, codec: cd})
+}
+
+// DoRequestAndDecode sends the request advertising every registered content
+// type in Accept, and decodes the response into v with the codec matching its
+// Content-Type.
+func (c *Client) DoRequestAndDecode(
+	ctx context.Context,
+	method, url string,
+	body io.Reader,
+	v interface{},
+) error {
+	return c.R(ctx).Method(method).URL(url).Body(body).Decode(v)
+}
+
+// registeredCodecs returns the codecs in use, which are never modified.
+func (c *Client) registeredCodecs() []codec {
+	c.codecsMu.RLock()
+	defer c.codecsMu.RUnlock()
+
+	if c.codecs == nil {
+		return defaultCodecs()
+	}
+
+	return c.codecs
+}
+
+func (c *Client) accept() string {
+	codecs := c.registeredCodecs()
+
+	types := make([]string, 0, len(codecs))
+	for _, cd := range codecs {
+		types = append(types, cd.contentType)
+	}
+
+	return strings.Join(types, ", ")
+}
+
+func (c *Client) decode(contentType string, data []byte, v interface{}) error {
+	cd, err := c.codecFor(contentType)
+	if err != nil {
+		return err
+	}
+
+	return cd.Decode(data, v)
+}
+
+func (c *Client) codecFor(contentType string) (Codec, error) {
+	codecs := c.registeredCodecs()
+
+	mediaType := ContentTypeJSON
+	if contentType != "" {
+		t, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrNoCodec, contentType)
+		}
+		mediaType = strings.ToLower(t)
+	}
+
+	for _, cd := range codecs {
+		if cd.contentType == mediaType {
+			return cd.codec, nil
+		}
+	}
+
+	// Structured syntax suffixes, e.g. application/problem+json.
+	for suffix, t := range map[string]string{"+json": ContentTypeJSON, "+xml": ContentTypeXML} {
+		if strings.HasSuffix(mediaType, suffix) {
+			return c.codecFor(t)
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrNoCodec, contentType)
+}
+
+func (d CSVCodec) Decode(data []byte, v interface{}) error {
+	r := csv.NewReader(bytes.NewReader(data))
+	if d.Comma != 0 {
+		r.Comma = d.Comma
+	}
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return err
+	}
+
+	if rows, ok := v.(*[][]string); ok {
+		*rows = records
+		return nil
+	}
+
+	slice := reflect.ValueOf(v)
+	if slice.Kind() != reflect.Pointer || slice.Elem().Kind() != reflect.Slice || slice.Elem().Type().Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("csv: cannot decode into %T", v)
+	}
+
+	slice = slice.Elem()
+	slice.SetLen(0)
+	if len(records) == 0 {
+		return nil
+	}
+
+	header := records[0]
+	itemType := slice.Type().Elem()
+
+	for line, record := range records[1:] {
+		item := reflect.New(itemType).Elem()
+		for col, name := range header {
+			if col >= len(record) {
+				break
+			}
+
+			field, ok := fieldByTag(item, "csv", name)
+			if !ok {
+				continue
+			}
+
+			if err := setField(field, record[col]); err != nil {
+				return fmt.Errorf("csv: line %d, column %q: %w", line+2, name, err)
+			}
+		}
+
+		slice.Set(reflect.Append(slice, item))
+	}
+
+	return nil
+}
+
+func (FormCodec) Decode(data []byte, v interface{}) error {
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+
+	switch t := v.(type) {
+	case *url.Values:
+		*t = values
+		return nil
+	case *map[string]string:
+		*t = make(map[string]string, len(values))
+		for k := range values {
+			(*t)[k] = values.Get(k)
+		}
+		return nil
+	}
+
+	item := reflect.ValueOf(v)
+	if item.Kind() != reflect.Pointer || item.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("form: cannot decode into %T", v)
+	}
+
+	item = item.Elem()
+	for name := range values {
+		field, ok := fieldByTag(item, "form", name)
+		if !ok {
+			continue
+		}
+
+		if err := setField(field, values.Get(name)); err != nil {
+			return fmt.Errorf("form: field %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// fieldByTag finds the field of item tagged with name, falling back to a case
+// insensitive match on the field name.
+func fieldByTag(item reflect.Value, tag, name string) (reflect.Value, bool) {
+	t := item.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		tagName, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+		if tagName == "-" {
+			continue
+		}
+		if tagName == name || (tagName == "" && strings.EqualFold(f.Name, name)) {
+			return item.Field(i), true
+		}
+	}
+
+	return reflect.Value{}, false
+}
+
+func setField(field reflect.Value, value string) error {
+	if u, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(value))
+	}
+
+	value = strings.TrimSpace(value)
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		if value == "" {
+			return nil
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value == "" {
+			return nil
+		}
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if value == "" {
+			return nil
+		}
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if value == "" {
+			return nil
+		}
+		n, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+
+	return nil
+}
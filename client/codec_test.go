@@ -0,0 +1,122 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type movement struct {
+	Date    string  `csv:"date" xml:"date"`
+	Amount  float64 `csv:"amount" xml:"amount"`
+	Account int     `csv:"account_id" xml:"account"`
+}
+
+func newContentServer(contentType, body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Write([]byte(body))
+	}))
+}
+
+func TestThatCSVIsDecodedIntoStructs(t *testing.T) {
+	server := newContentServer("text/csv; charset=utf-8", "date,amount,account_id\n2024-01-02,10.5,7\n2024-01-03,-3,8\n")
+	defer server.Close()
+
+	var movements []movement
+	err := NewClient(server.Client()).DoRequestAndDecode(context.Background(), "GET", server.URL, nil, &movements)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []movement{
+		{Date: "2024-01-02", Amount: 10.5, Account: 7},
+		{Date: "2024-01-03", Amount: -3, Account: 8},
+	}, movements)
+}
+
+func TestThatXMLIsDecoded(t *testing.T) {
+	server := newContentServer("text/xml", "<movement><date>2024-01-02</date><amount>1.5</amount><account>3</account></movement>")
+	defer server.Close()
+
+	var m movement
+	err := NewClient(server.Client()).DoRequestAndDecode(context.Background(), "GET", server.URL, nil, &m)
+
+	assert.NoError(t, err)
+	assert.Equal(t, movement{Date: "2024-01-02", Amount: 1.5, Account: 3}, m)
+}
+
+func TestThatFormIsDecodedIntoStruct(t *testing.T) {
+	var out struct {
+		Status string `form:"status"`
+		Count  int
+	}
+
+	err := FormCodec{}.Decode([]byte("status=ok&count=2"), &out)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", out.Status)
+	assert.Equal(t, 2, out.Count)
+}
+
+func TestThatCustomCodecsAreUsedAndAdvertised(t *testing.T) {
+	server := newContentServer("application/vnd.bank+fixed", "HELLO")
+	defer server.Close()
+
+	cl := NewClient(server.Client())
+	cl.RegisterCodec("application/vnd.bank+fixed", CodecFunc(func(data []byte, v interface{}) error {
+		*v.(*string) = strings.ToLower(string(data))
+		return nil
+	}))
+
+	var out string
+	err := cl.DoRequestAndDecode(context.Background(), "GET", server.URL, nil, &out)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", out)
+	assert.Contains(t, cl.accept(), "application/vnd.bank+fixed")
+	assert.Contains(t, cl.accept(), ContentTypeJSON)
+}
+
+func TestThatCodecsCanBeRegisteredWhileDecoding(t *testing.T) {
+	server := newContentServer("application/vnd.bank+fixed", "HELLO")
+	defer server.Close()
+
+	cl := NewClient(server.Client())
+	lower := CodecFunc(func(data []byte, v interface{}) error {
+		*v.(*string) = strings.ToLower(string(data))
+		return nil
+	})
+	cl.RegisterCodec("application/vnd.bank+fixed", lower)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			cl.RegisterCodec("application/vnd.bank+fixed", lower)
+		}()
+		go func() {
+			defer wg.Done()
+			var out string
+			assert.NoError(t, cl.DoRequestAndDecode(context.Background(), "GET", server.URL, nil, &out))
+			assert.Equal(t, "hello", out)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestThatUnknownContentTypeReturnsError(t *testing.T) {
+	server := newContentServer("application/pdf", "%PDF")
+	defer server.Close()
+
+	var out url.Values
+	err := NewClient(server.Client()).DoRequestAndDecode(context.Background(), "GET", server.URL, nil, &out)
+
+	assert.True(t, errors.Is(err, ErrNoCodec))
+}
@@ -0,0 +1,247 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/propertechnologies/monitor/client"
+)
+
+const persistedQueryNotFound = "PERSISTED_QUERY_NOT_FOUND"
+
+type (
+	// Client posts GraphQL operations through a client.Client, so requests
+	// keep the authorization, flow-id and trace headers.
+	Client struct {
+		client   *client.Client
+		endpoint string
+	}
+
+	Request struct {
+		Query         string
+		OperationName string
+		Variables     map[string]interface{}
+		// Hash is the sha256 of a query registered on the server. When set
+		// without Query only the hash is sent.
+		Hash string
+		// Persisted sends the hash of Query first and only falls back to the
+		// full document when the server does not know it.
+		Persisted bool
+	}
+
+	Location struct {
+		Line   int `json:"line"`
+		Column int `json:"column"`
+	}
+
+	// Error is an entry of the "errors" array of a GraphQL response.
+	Error struct {
+		Message    string                 `json:"message"`
+		Path       []interface{}          `json:"path,omitempty"`
+		Locations  []Location             `json:"locations,omitempty"`
+		Extensions map[string]interface{} `json:"extensions,omitempty"`
+	}
+
+	// Errors is returned when the response carries an "errors" array. Data
+	// decoded from a partial response is still returned alongside it.
+	Errors []*Error
+
+	payload struct {
+		Query         string                 `json:"query,omitempty"`
+		OperationName string                 `json:"operationName,omitempty"`
+		Variables     map[string]interface{} `json:"variables,omitempty"`
+		Extensions    *extensions            `json:"extensions,omitempty"`
+	}
+
+	extensions struct {
+		PersistedQuery persistedQuery `json:"persistedQuery"`
+	}
+
+	persistedQuery struct {
+		Version    int    `json:"version"`
+		Sha256Hash string `json:"sha256Hash"`
+	}
+
+	response struct {
+		Data   json.RawMessage `json:"data"`
+		Errors Errors          `json:"errors"`
+	}
+)
+
+func NewClient(c *client.Client, endpoint string) *Client {
+	return &Client{client: c, endpoint: endpoint}
+}
+
+// Do runs the operation and decodes its "data" into T.
+func Do[T any](ctx context.Context, c *Client, req Request) (T, error) {
+	var data T
+
+	res, err := c.send(ctx, req)
+	if err != nil {
+		return data, err
+	}
+
+	if len(res.Data) != 0 && string(res.Data) != "null" {
+		if err := json.Unmarshal(res.Data, &data); err != nil {
+			return data, fmt.Errorf("decoding graphql data: %w", err)
+		}
+	}
+
+	if len(res.Errors) != 0 {
+		return data, res.Errors
+	}
+
+	return data, nil
+}
+
+// Hash returns the persisted-query hash of a query document.
+func Hash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *Client) send(ctx context.Context, req Request) (*response, error) {
+	hash := req.Hash
+	if hash == "" && req.Persisted && req.Query != "" {
+		hash = Hash(req.Query)
+	}
+
+	if hash == "" {
+		return c.post(ctx, payload{
+			Query:         req.Query,
+			OperationName: req.OperationName,
+			Variables:     req.Variables,
+		})
+	}
+
+	p := payload{
+		OperationName: req.OperationName,
+		Variables:     req.Variables,
+		Extensions:    &extensions{PersistedQuery: persistedQuery{Version: 1, Sha256Hash: hash}},
+	}
+
+	res, err := c.post(ctx, p)
+	if err != nil || req.Query == "" || !res.Errors.persistedQueryNotFound() {
+		return res, err
+	}
+
+	// The server doesn't know the hash yet, register it with the document.
+	p.Query = req.Query
+
+	return c.post(ctx, p)
+}
+
+func (c *Client) post(ctx context.Context, p payload) (*response, error) {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRes, err := c.client.R(ctx).
+		Method(http.MethodPost).
+		URL(c.endpoint).
+		Body(bytes.NewReader(body)).
+		ContentType("application/json").
+		DoRaw()
+	if err != nil {
+		return nil, err
+	}
+	defer httpRes.Body.Close()
+
+	b, err := io.ReadAll(httpRes.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var res response
+	decodeErr := json.Unmarshal(b, &res)
+
+	if httpRes.StatusCode < http.StatusOK || httpRes.StatusCode >= http.StatusMultipleChoices {
+		// GraphQL over HTTP servers answer errors with 4xx and an errors
+		// body, keep them typed.
+		if decodeErr == nil && len(res.Errors) != 0 {
+			return &res, nil
+		}
+
+		return nil, fmt.Errorf("status %d, message %s", httpRes.StatusCode, string(b))
+	}
+
+	if decodeErr != nil {
+		return nil, fmt.Errorf("decoding graphql response: %w", decodeErr)
+	}
+
+	return &res, nil
+}
+
+func (e *Error) Error() string {
+	if len(e.Path) == 0 {
+		return e.Message
+	}
+
+	path := make([]string, 0, len(e.Path))
+	for _, p := range e.Path {
+		path = append(path, fmt.Sprint(p))
+	}
+
+	return fmt.Sprintf("%s (path: %s)", e.Message, strings.Join(path, "."))
+}
+
+// Code returns extensions.code, the conventional machine readable error
+// code, or an empty string.
+func (e *Error) Code() string {
+	code, _ := e.Extensions["code"].(string)
+	return code
+}
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+
+	return "graphql: " + strings.Join(messages, "; ")
+}
+
+// Unwrap exposes each entry so errors.As can reach a single *Error.
+func (e Errors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, err := range e {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+// HasCode reports whether err contains a GraphQL error with the given code.
+func HasCode(err error, code string) bool {
+	var errs Errors
+	if !errors.As(err, &errs) {
+		return false
+	}
+
+	for _, e := range errs {
+		if e.Code() == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e Errors) persistedQueryNotFound() bool {
+	for _, err := range e {
+		if err.Code() == persistedQueryNotFound || err.Message == "PersistedQueryNotFound" {
+			return true
+		}
+	}
+
+	return false
+}
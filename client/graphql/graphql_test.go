@@ -0,0 +1,132 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/propertechnologies/monitor/client"
+	"github.com/stretchr/testify/assert"
+)
+
+type account struct {
+	Account struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"account"`
+}
+
+func TestThatDataIsDecodedAndHeadersAreKept(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer myToken", r.Header.Get("Authorization"))
+
+		var p payload
+		json.NewDecoder(r.Body).Decode(&p)
+		assert.Equal(t, "42", p.Variables["id"])
+
+		w.Write([]byte(`{"data":{"account":{"id":"42","name":"savings"}}}`))
+	}))
+	defer server.Close()
+
+	gql := NewClient(client.NewClientWithTokent(server.Client(), "myToken"), server.URL)
+
+	data, err := Do[account](context.Background(), gql, Request{
+		Query:     "query($id: ID!) { account(id: $id) { id name } }",
+		Variables: map[string]interface{}{"id": "42"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "savings", data.Account.Name)
+}
+
+func TestThatErrorsAreTyped(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{"account":null},"errors":[{"message":"not allowed","path":["account",0],"extensions":{"code":"FORBIDDEN"}}]}`))
+	}))
+	defer server.Close()
+
+	gql := NewClient(client.NewClient(server.Client()), server.URL)
+
+	_, err := Do[account](context.Background(), gql, Request{Query: "{ account { id } }"})
+
+	var gqlErr *Error
+	assert.True(t, errors.As(err, &gqlErr))
+	assert.Equal(t, "FORBIDDEN", gqlErr.Code())
+	assert.Equal(t, []interface{}{"account", float64(0)}, gqlErr.Path)
+	assert.True(t, HasCode(err, "FORBIDDEN"))
+	assert.Equal(t, "graphql: not allowed (path: account.0)", err.Error())
+}
+
+func TestThatPersistedQueryIsRegisteredWhenUnknown(t *testing.T) {
+	query := "{ account { id } }"
+	var calls []payload
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p payload
+		json.NewDecoder(r.Body).Decode(&p)
+		calls = append(calls, p)
+
+		if p.Query == "" {
+			w.Write([]byte(`{"errors":[{"message":"PersistedQueryNotFound","extensions":{"code":"PERSISTED_QUERY_NOT_FOUND"}}]}`))
+			return
+		}
+
+		w.Write([]byte(`{"data":{"account":{"id":"1"}}}`))
+	}))
+	defer server.Close()
+
+	gql := NewClient(client.NewClient(server.Client()), server.URL)
+
+	data, err := Do[account](context.Background(), gql, Request{Query: query, Persisted: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "1", data.Account.ID)
+	assert.Len(t, calls, 2)
+	assert.Equal(t, Hash(query), calls[0].Extensions.PersistedQuery.Sha256Hash)
+	assert.Equal(t, query, calls[1].Query)
+}
+
+func TestThatErrorsOfNon2xxResponsesAreTyped(t *testing.T) {
+	query := "{ account { id } }"
+	var calls int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p payload
+		json.NewDecoder(r.Body).Decode(&p)
+		calls++
+
+		w.Header().Set("Content-Type", "application/json")
+		if p.Query == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"errors":[{"message":"PersistedQueryNotFound","extensions":{"code":"PERSISTED_QUERY_NOT_FOUND"}}]}`))
+			return
+		}
+
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"errors":[{"message":"invalid id","extensions":{"code":"BAD_USER_INPUT"}}]}`))
+	}))
+	defer server.Close()
+
+	gql := NewClient(client.NewClient(server.Client()), server.URL)
+
+	_, err := Do[account](context.Background(), gql, Request{Query: query, Persisted: true})
+
+	assert.Equal(t, 2, calls)
+	assert.True(t, HasCode(err, "BAD_USER_INPUT"))
+}
+
+func TestThatNon2xxResponsesWithoutErrorsFailWithTheStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	gql := NewClient(client.NewClient(server.Client()), server.URL)
+
+	_, err := Do[account](context.Background(), gql, Request{Query: "{ account { id } }"})
+
+	assert.ErrorContains(t, err, "status 502")
+}
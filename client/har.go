@@ -0,0 +1,771 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/propertechnologies/monitor/context_util"
+)
+
+const (
+	harVersion  = "1.2"
+	harRedacted = "[REDACTED]"
+
+	defaultHARMaxBytes     = 5 << 20
+	defaultHARMaxBodyBytes = 64 << 10
+	defaultHARMaxFlows     = 100
+)
+
+var (
+	defaultRedactedHeaders = []string{
+		"Authorization",
+		"Proxy-Authorization",
+		"Cookie",
+		"Set-Cookie",
+		"X-Api-Key",
+	}
+
+	defaultRedactedFields = []string{
+		"password",
+		"passwd",
+		"secret",
+		"token",
+		"access_token",
+		"refresh_token",
+		"id_token",
+		"client_secret",
+		"api_key",
+		"apikey",
+		"otp",
+		"pin",
+	}
+)
+
+type (
+	// HAROptions configures a HARRecorder. Zero values fall back to sane
+	// defaults.
+	HAROptions struct {
+		// MaxBytes bounds the recorded size of a single flow. When exceeded
+		// the oldest entries of the flow are discarded.
+		MaxBytes int
+		// MaxBodyBytes bounds the size of each recorded request and
+		// response body. Longer bodies are truncated.
+		MaxBodyBytes int
+		// MaxFlows bounds the number of flows kept. When exceeded the
+		// least recently recorded flow is discarded. Defaults to 100.
+		MaxFlows int
+		// RedactHeaders are header names whose values are never recorded.
+		// They are added to the default list.
+		RedactHeaders []string
+		// RedactFields are JSON and form field names whose values are never
+		// recorded. They are added to the default list.
+		RedactFields []string
+	}
+
+	// HARRecorder keeps the HTTP exchanges of a Client grouped by flow-id
+	// so they can be exported as HAR 1.2 documents.
+	HARRecorder struct {
+		mu            sync.Mutex
+		maxBytes      int
+		maxBodyBytes  int
+		maxFlows      int
+		redactHeaders map[string]bool
+		redactFields  map[string]bool
+		// jsonFields matches the redacted fields in JSON bodies that can't
+		// be parsed, such as the ones cut at the recording limit.
+		jsonFields *regexp.Regexp
+		flows      map[string]*harFlow
+		// seq orders the flows by their last recorded entry.
+		seq uint64
+	}
+
+	// recordedBody records a response body as the caller reads it, up to
+	// the recorder's body limit, and stores the exchange at EOF or Close.
+	recordedBody struct {
+		io.ReadCloser
+		rec   *harRecording
+		res   *http.Response
+		limit int
+		buf   bytes.Buffer
+		err   error
+		once  sync.Once
+	}
+
+	harFlow struct {
+		entries []harEntry
+		sizes   []int
+		size    int
+		seq     uint64
+	}
+
+	// limitedBody is a request body whose first bytes were read for the
+	// recording.
+	limitedBody struct {
+		io.Reader
+		io.Closer
+	}
+
+	harRecording struct {
+		recorder *HARRecorder
+		flowID   string
+		request  *http.Request
+		body     []byte
+		started  time.Time
+
+		mu           sync.Mutex
+		dnsStart     time.Time
+		dnsDone      time.Time
+		connectStart time.Time
+		connectDone  time.Time
+		tlsStart     time.Time
+		tlsDone      time.Time
+		wroteRequest time.Time
+		firstByte    time.Time
+	}
+
+	harDocument struct {
+		Log harLog `json:"log"`
+	}
+
+	harLog struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	}
+
+	harCreator struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+
+	harEntry struct {
+		StartedDateTime string      `json:"startedDateTime"`
+		Time            float64     `json:"time"`
+		Request         harRequest  `json:"request"`
+		Response        harResponse `json:"response"`
+		Cache           struct{}    `json:"cache"`
+		Timings         harTimings  `json:"timings"`
+		Error           string      `json:"_error,omitempty"`
+	}
+
+	harRequest struct {
+		Method      string       `json:"method"`
+		URL         string       `json:"url"`
+		HTTPVersion string       `json:"httpVersion"`
+		Cookies     []harNameVal `json:"cookies"`
+		Headers     []harNameVal `json:"headers"`
+		QueryString []harNameVal `json:"queryString"`
+		PostData    *harPostData `json:"postData,omitempty"`
+		HeadersSize int          `json:"headersSize"`
+		BodySize    int          `json:"bodySize"`
+	}
+
+	harResponse struct {
+		Status      int          `json:"status"`
+		StatusText  string       `json:"statusText"`
+		HTTPVersion string       `json:"httpVersion"`
+		Cookies     []harNameVal `json:"cookies"`
+		Headers     []harNameVal `json:"headers"`
+		Content     harContent   `json:"content"`
+		RedirectURL string       `json:"redirectURL"`
+		HeadersSize int          `json:"headersSize"`
+		BodySize    int          `json:"bodySize"`
+	}
+
+	harNameVal struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+
+	harPostData struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+	}
+
+	harContent struct {
+		Size     int    `json:"size"`
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text,omitempty"`
+	}
+
+	harTimings struct {
+		Blocked float64 `json:"blocked"`
+		DNS     float64 `json:"dns"`
+		Connect float64 `json:"connect"`
+		Send    float64 `json:"send"`
+		Wait    float64 `json:"wait"`
+		Receive float64 `json:"receive"`
+		SSL     float64 `json:"ssl"`
+	}
+)
+
+func NewHARRecorder(opts HAROptions) *HARRecorder {
+	r := &HARRecorder{
+		maxBytes:      opts.MaxBytes,
+		maxBodyBytes:  opts.MaxBodyBytes,
+		maxFlows:      opts.MaxFlows,
+		redactHeaders: map[string]bool{},
+		redactFields:  map[string]bool{},
+		flows:         map[string]*harFlow{},
+	}
+
+	if r.maxBytes <= 0 {
+		r.maxBytes = defaultHARMaxBytes
+	}
+	if r.maxBodyBytes <= 0 {
+		r.maxBodyBytes = defaultHARMaxBodyBytes
+	}
+	if r.maxFlows <= 0 {
+		r.maxFlows = defaultHARMaxFlows
+	}
+
+	for _, h := range append(defaultRedactedHeaders, opts.RedactHeaders...) {
+		r.redactHeaders[http.CanonicalHeaderKey(h)] = true
+	}
+	var fields []string
+	for _, f := range append(defaultRedactedFields, opts.RedactFields...) {
+		r.redactFields[strings.ToLower(f)] = true
+		fields = append(fields, regexp.QuoteMeta(f))
+	}
+	r.jsonFields = regexp.MustCompile(`(?i)("(?:` + strings.Join(fields, "|") + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`)
+
+	return r
+}
+
+// WithRecorder makes the client record every exchange into r.
+func (c *Client) WithRecorder(r *HARRecorder) *Client {
+	c.recorder = r
+	return c
+}
+
+// FlowIDs returns the flow-ids that have recorded entries.
+func (r *HARRecorder) FlowIDs() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make([]string, 0, len(r.flows))
+	for id := range r.flows {
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// HAR returns the HAR 1.2 document of the given flow.
+func (r *HARRecorder) HAR(flowID string) ([]byte, error) {
+	r.mu.Lock()
+	entries := []harEntry{}
+	if f, ok := r.flows[flowID]; ok {
+		entries = append(entries, f.entries...)
+	}
+	r.mu.Unlock()
+
+	return json.MarshalIndent(harDocument{
+		Log: harLog{
+			Version: harVersion,
+			Creator: harCreator{Name: "propertechnologies/monitor", Version: harVersion},
+			Entries: entries,
+		},
+	}, "", "  ")
+}
+
+// WriteHAR writes the HAR document of the given flow to w.
+func (r *HARRecorder) WriteHAR(w io.Writer, flowID string) error {
+	b, err := r.HAR(flowID)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+
+	return err
+}
+
+// WriteFile writes the HAR document of the given flow to path.
+func (r *HARRecorder) WriteFile(path string, flowID string) error {
+	b, err := r.HAR(flowID)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, b, 0o644)
+}
+
+// Reset drops every entry recorded for the given flow.
+func (r *HARRecorder) Reset(flowID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.flows, flowID)
+}
+
+func (r *HARRecorder) add(flowID string, e harEntry) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, ok := r.flows[flowID]
+	if !ok {
+		if len(r.flows) >= r.maxFlows {
+			r.evictFlow()
+		}
+
+		f = &harFlow{}
+		r.flows[flowID] = f
+	}
+
+	r.seq++
+	f.seq = r.seq
+
+	f.entries = append(f.entries, e)
+	f.sizes = append(f.sizes, len(b))
+	f.size += len(b)
+
+	for f.size > r.maxBytes && len(f.entries) > 1 {
+		f.size -= f.sizes[0]
+		f.entries = f.entries[1:]
+		f.sizes = f.sizes[1:]
+	}
+}
+
+// evictFlow discards the flow whose last entry is the oldest.
+func (r *HARRecorder) evictFlow() {
+	var (
+		oldest string
+		seq    uint64
+	)
+	for id, f := range r.flows {
+		if seq == 0 || f.seq < seq {
+			oldest, seq = id, f.seq
+		}
+	}
+
+	delete(r.flows, oldest)
+}
+
+// startRecording prepares req to be recorded. It returns the request that
+// must be sent, which carries an httptrace to collect timings.
+func (c *Client) startRecording(ctx context.Context, req *http.Request) (*http.Request, *harRecording) {
+	if c.recorder == nil {
+		return req, nil
+	}
+
+	rec := &harRecording{
+		recorder: c.recorder,
+		flowID:   req.Header.Get("X-Flow-Id"),
+		body:     readRequestBody(req, c.recorder.maxBodyBytes+1),
+		started:  time.Now(),
+	}
+	if rec.flowID == "" {
+		rec.flowID = context_util.GetFlowID(ctx)
+	}
+
+	rec.request = req.WithContext(httptrace.WithClientTrace(req.Context(), rec.clientTrace()))
+
+	return rec.request, rec
+}
+
+func (rec *harRecording) clientTrace() *httptrace.ClientTrace {
+	mark := func(t *time.Time) {
+		rec.mu.Lock()
+		*t = time.Now()
+		rec.mu.Unlock()
+	}
+
+	return &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { mark(&rec.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { mark(&rec.dnsDone) },
+		ConnectStart:         func(string, string) { mark(&rec.connectStart) },
+		ConnectDone:          func(string, string, error) { mark(&rec.connectDone) },
+		TLSHandshakeStart:    func() { mark(&rec.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { mark(&rec.tlsDone) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { mark(&rec.wroteRequest) },
+		GotFirstResponseByte: func() { mark(&rec.firstByte) },
+	}
+}
+
+// finish stores the exchange. It is a no-op on a nil recording.
+func (rec *harRecording) finish(res *http.Response, body []byte, err error) {
+	if rec == nil {
+		return
+	}
+
+	r := rec.recorder
+	end := time.Now()
+
+	entry := harEntry{
+		StartedDateTime: rec.started.UTC().Format(time.RFC3339Nano),
+		Time:            millis(rec.started, end),
+		Request:         r.harRequest(rec.request, rec.body),
+		Response:        r.harResponse(res, body),
+		Timings:         rec.timings(end),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	r.add(rec.flowID, entry)
+}
+
+// recordBody makes the exchange be stored once the caller is done with the
+// response body, without buffering it all.
+func (rec *harRecording) recordBody(res *http.Response) {
+	res.Body = &recordedBody{
+		ReadCloser: res.Body,
+		rec:        rec,
+		res:        res,
+		// One more byte than kept, so the body is marked as truncated.
+		limit: rec.recorder.maxBodyBytes + 1,
+	}
+}
+
+func (b *recordedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if room := b.limit - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(n, room)])
+	}
+
+	if err != nil {
+		if err != io.EOF {
+			b.err = err
+		}
+		b.finish()
+	}
+
+	return n, err
+}
+
+func (b *recordedBody) Close() error {
+	b.finish()
+	return b.ReadCloser.Close()
+}
+
+func (b *recordedBody) finish() {
+	b.once.Do(func() {
+		b.rec.finish(b.res, b.buf.Bytes(), b.err)
+	})
+}
+
+func (rec *harRecording) timings(end time.Time) harTimings {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	t := harTimings{
+		Blocked: -1,
+		DNS:     -1,
+		Connect: -1,
+		SSL:     -1,
+		Wait:    millis(rec.started, end),
+	}
+
+	if !rec.dnsStart.IsZero() && !rec.dnsDone.IsZero() {
+		t.DNS = millis(rec.dnsStart, rec.dnsDone)
+	}
+	if !rec.connectStart.IsZero() && !rec.connectDone.IsZero() {
+		t.Connect = millis(rec.connectStart, rec.connectDone)
+	}
+	if !rec.tlsStart.IsZero() && !rec.tlsDone.IsZero() {
+		t.SSL = millis(rec.tlsStart, rec.tlsDone)
+	}
+	if !rec.wroteRequest.IsZero() && !rec.firstByte.IsZero() {
+		connected := rec.started
+		for _, ts := range []time.Time{rec.dnsDone, rec.connectDone, rec.tlsDone} {
+			if ts.After(connected) {
+				connected = ts
+			}
+		}
+
+		t.Send = millis(connected, rec.wroteRequest)
+		t.Wait = millis(rec.wroteRequest, rec.firstByte)
+		t.Receive = millis(rec.firstByte, end)
+	}
+
+	return t
+}
+
+func (r *HARRecorder) harRequest(req *http.Request, body []byte) harRequest {
+	h := harRequest{
+		Method:      req.Method,
+		URL:         r.redactURL(req.URL),
+		HTTPVersion: httpVersion(req.Proto),
+		Cookies:     []harNameVal{},
+		Headers:     r.headers(req.Header),
+		QueryString: r.query(req.URL),
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+
+	if body != nil {
+		contentType := req.Header.Get("Content-Type")
+		h.PostData = &harPostData{
+			MimeType: contentType,
+			Text:     r.redactBody(contentType, body),
+		}
+	}
+
+	return h
+}
+
+func (r *HARRecorder) harResponse(res *http.Response, body []byte) harResponse {
+	h := harResponse{
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []harNameVal{},
+		Headers:     []harNameVal{},
+		HeadersSize: -1,
+		BodySize:    -1,
+	}
+	if res == nil {
+		return h
+	}
+
+	contentType := res.Header.Get("Content-Type")
+
+	h.Status = res.StatusCode
+	h.StatusText = http.StatusText(res.StatusCode)
+	h.HTTPVersion = httpVersion(res.Proto)
+	h.Headers = r.headers(res.Header)
+	h.RedirectURL = res.Header.Get("Location")
+	h.BodySize = len(body)
+	h.Content = harContent{
+		Size:     len(body),
+		MimeType: contentType,
+		Text:     r.redactBody(contentType, body),
+	}
+
+	return h
+}
+
+func (r *HARRecorder) headers(header http.Header) []harNameVal {
+	values := []harNameVal{}
+	for name, vs := range header {
+		for _, v := range vs {
+			if r.redactHeaders[http.CanonicalHeaderKey(name)] {
+				v = harRedacted
+			}
+			values = append(values, harNameVal{Name: name, Value: v})
+		}
+	}
+
+	return values
+}
+
+func (r *HARRecorder) query(u *url.URL) []harNameVal {
+	values := []harNameVal{}
+	if u == nil {
+		return values
+	}
+
+	for name, vs := range u.Query() {
+		for _, v := range vs {
+			if r.redactFields[strings.ToLower(name)] {
+				v = harRedacted
+			}
+			values = append(values, harNameVal{Name: name, Value: v})
+		}
+	}
+
+	return values
+}
+
+func (r *HARRecorder) redactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+
+	c := *u
+	if c.User != nil {
+		c.User = url.User(c.User.Username())
+	}
+	c.RawQuery = r.redactQuery(c.RawQuery)
+
+	return c.String()
+}
+
+// redactQuery replaces the values of the redacted fields in place, keeping
+// the order and encoding of the others as sent.
+func (r *HARRecorder) redactQuery(query string) string {
+	if query == "" {
+		return ""
+	}
+
+	parts := strings.Split(query, "&")
+	for i, part := range parts {
+		key, _, _ := strings.Cut(part, "=")
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+
+		if r.redactFields[strings.ToLower(name)] {
+			parts[i] = key + "=" + url.QueryEscape(harRedacted)
+		}
+	}
+
+	return strings.Join(parts, "&")
+}
+
+func (r *HARRecorder) redactBody(contentType string, body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	switch {
+	case strings.Contains(contentType, "json"):
+		redacted, ok := r.redactJSON(body)
+		if !ok {
+			redacted = r.jsonFields.ReplaceAll(body, []byte(`${1}"`+harRedacted+`"`))
+		}
+		body = redacted
+	case strings.Contains(contentType, "application/x-www-form-urlencoded"):
+		body = []byte(r.redactQuery(string(body)))
+	}
+
+	if len(body) > r.maxBodyBytes {
+		// Cut on a rune boundary so the HAR stays valid UTF-8.
+		n := r.maxBodyBytes
+		for n > 0 && !utf8.RuneStart(body[n]) {
+			n--
+		}
+
+		return string(body[:n]) + "...[truncated]"
+	}
+
+	return string(body)
+}
+
+// redactJSON replaces the values of the redacted fields in place, so the
+// rest of the body is recorded byte for byte as sent. It reports false when
+// body isn't valid JSON.
+func (r *HARRecorder) redactJSON(body []byte) ([]byte, bool) {
+	type level struct {
+		object bool
+		// key is set when the next token of an object is a key.
+		key bool
+	}
+
+	var (
+		d      = json.NewDecoder(bytes.NewReader(body))
+		stack  []level
+		values [][2]int
+	)
+
+	// valueDone expects the next key of the enclosing object, if any.
+	valueDone := func() {
+		if len(stack) != 0 && stack[len(stack)-1].object {
+			stack[len(stack)-1].key = true
+		}
+	}
+
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false
+		}
+
+		if len(stack) != 0 && stack[len(stack)-1].key {
+			if tok == json.Delim('}') {
+				stack = stack[:len(stack)-1]
+				valueDone()
+				continue
+			}
+
+			stack[len(stack)-1].key = false
+			if name, _ := tok.(string); r.redactFields[strings.ToLower(name)] {
+				var raw json.RawMessage
+				if err := d.Decode(&raw); err != nil {
+					return nil, false
+				}
+
+				end := int(d.InputOffset())
+				values = append(values, [2]int{end - len(raw), end})
+				valueDone()
+			}
+			continue
+		}
+
+		switch tok {
+		case json.Delim('{'):
+			stack = append(stack, level{object: true, key: true})
+		case json.Delim('['):
+			stack = append(stack, level{})
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+			valueDone()
+		default:
+			valueDone()
+		}
+	}
+
+	if len(values) == 0 {
+		return body, true
+	}
+
+	var b bytes.Buffer
+	last := 0
+	for _, v := range values {
+		b.Write(body[last:v[0]])
+		b.WriteString(`"` + harRedacted + `"`)
+		last = v[1]
+	}
+	b.Write(body[last:])
+
+	return b.Bytes(), true
+}
+
+// readRequestBody returns a copy of the first limit bytes of the request
+// body, leaving req readable.
+func readRequestBody(req *http.Request, limit int) []byte {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err == nil {
+			defer rc.Close()
+			b, err := io.ReadAll(io.LimitReader(rc, int64(limit)))
+			if err == nil {
+				return b
+			}
+		}
+	}
+
+	b, err := io.ReadAll(io.LimitReader(req.Body, int64(limit)))
+	req.Body = &limitedBody{Reader: io.MultiReader(bytes.NewReader(b), req.Body), Closer: req.Body}
+	if err != nil {
+		return nil
+	}
+
+	return b
+}
+
+func httpVersion(proto string) string {
+	if proto == "" {
+		return "HTTP/1.1"
+	}
+
+	return proto
+}
+
+func millis(from, to time.Time) float64 {
+	return float64(to.Sub(from)) / float64(time.Millisecond)
+}
@@ -0,0 +1,184 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThatRecorderWritesHARWithRedactedValues(t *testing.T) {
+	t.Setenv("FLOW", "flow-1")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"abc","name":"bob"}`))
+	}))
+	defer server.Close()
+
+	recorder := NewHARRecorder(HAROptions{})
+	cl := NewClientWithTokent(server.Client(), "myToken").WithRecorder(recorder)
+
+	_, err := cl.DoRequestWithContentType(
+		context.Background(),
+		"POST",
+		server.URL+"?apikey=123",
+		strings.NewReader(`{"user":"bob","password":"hunter2"}`),
+		"application/json",
+	)
+	assert.NoError(t, err)
+
+	b, err := recorder.HAR("flow-1")
+	assert.NoError(t, err)
+
+	var doc harDocument
+	assert.NoError(t, json.Unmarshal(b, &doc))
+	assert.Equal(t, "1.2", doc.Log.Version)
+	assert.Len(t, doc.Log.Entries, 1)
+
+	entry := doc.Log.Entries[0]
+	assert.Equal(t, 200, entry.Response.Status)
+	assert.Contains(t, entry.Request.URL, "apikey=%5BREDACTED%5D")
+	assert.NotContains(t, entry.Request.PostData.Text, "hunter2")
+	assert.Contains(t, entry.Request.PostData.Text, `"user":"bob"`)
+	assert.NotContains(t, entry.Response.Content.Text, "abc")
+	assert.GreaterOrEqual(t, entry.Timings.Wait, float64(0))
+
+	for _, h := range entry.Request.Headers {
+		if h.Name == "Authorization" {
+			assert.Equal(t, harRedacted, h.Value)
+		}
+	}
+}
+
+func TestThatRecorderDropsOldestEntriesWhenFlowIsTooBig(t *testing.T) {
+	recorder := NewHARRecorder(HAROptions{MaxBytes: 1500})
+	cl := NewClient(newHTTPClientMock()).WithRecorder(recorder)
+
+	for i := 0; i < 10; i++ {
+		_, err := cl.DoRequest(context.Background(), "GET", "http://example.com", nil)
+		assert.NoError(t, err)
+	}
+
+	recorder.mu.Lock()
+	flow := recorder.flows[GetFlowID()]
+	recorder.mu.Unlock()
+
+	assert.Less(t, len(flow.entries), 10)
+	assert.LessOrEqual(t, flow.size, 1500)
+}
+
+func TestThatRawRequestsAreRecordedAndBodyIsStillReadable(t *testing.T) {
+	recorder := NewHARRecorder(HAROptions{})
+	cl := NewClient(newHTTPClientMock()).WithRecorder(recorder)
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	res, err := cl.DoRequestRaw(context.Background(), req)
+	assert.NoError(t, err)
+
+	body, err := io.ReadAll(res.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello, client", string(body))
+
+	b, err := recorder.HAR("")
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "Hello, client")
+}
+
+type failingBodyClient struct{}
+
+func (failingBodyClient) Do(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(io.MultiReader(strings.NewReader("partial"), iotest.ErrReader(io.ErrUnexpectedEOF))),
+	}, nil
+}
+
+func TestThatRawBodyReadErrorsReachTheCaller(t *testing.T) {
+	recorder := NewHARRecorder(HAROptions{})
+	cl := NewClient(failingBodyClient{}).WithRecorder(recorder)
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	res, err := cl.DoRequestRaw(context.Background(), req)
+	assert.NoError(t, err)
+
+	_, err = io.ReadAll(res.Body)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	res.Body.Close()
+
+	b, _ := recorder.HAR("")
+	assert.Contains(t, string(b), "unexpected EOF")
+	assert.Contains(t, string(b), "partial")
+}
+
+func TestThatRecordedURLKeepsTheQueryAsSent(t *testing.T) {
+	recorder := NewHARRecorder(HAROptions{})
+	u, _ := url.Parse("http://example.com/path?z=1&token=abc&a=x%20y")
+
+	assert.Equal(t, "http://example.com/path?z=1&token=%5BREDACTED%5D&a=x%20y", recorder.redactURL(u))
+}
+
+func TestThatTruncatedBodiesStayValidAndRedacted(t *testing.T) {
+	recorder := NewHARRecorder(HAROptions{MaxBodyBytes: 30})
+
+	body := recorder.redactBody("text/plain", []byte(strings.Repeat("é", 20)))
+	assert.True(t, utf8.ValidString(body))
+	assert.True(t, strings.HasSuffix(body, "...[truncated]"))
+
+	// Bodies cut at the recording limit are no longer valid JSON.
+	body = recorder.redactBody("application/json", []byte(`{"password": "hunter2", "items": [1, 2`))
+	assert.NotContains(t, body, "hunter2")
+}
+
+func TestThatJSONBodiesAreRecordedAsSent(t *testing.T) {
+	recorder := NewHARRecorder(HAROptions{})
+
+	body := recorder.redactBody("application/json", []byte(`{"z": 12345678901234567891, "q": "a<b&c", "token": {"value": "abc"}, "items": [{"password":"hunter2"}, "x"]}`))
+
+	assert.Equal(t, `{"z": 12345678901234567891, "q": "a<b&c", "token": "[REDACTED]", "items": [{"password":"[REDACTED]"}, "x"]}`, body)
+}
+
+func TestThatLargeRequestBodiesAreOnlyReadUpToTheLimit(t *testing.T) {
+	var sent int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		sent = len(b)
+	}))
+	defer server.Close()
+
+	recorder := NewHARRecorder(HAROptions{MaxBodyBytes: 100})
+	cl := NewClient(server.Client()).WithRecorder(recorder)
+
+	// A reader without GetBody, like a streamed upload.
+	req, _ := http.NewRequest("POST", server.URL, io.MultiReader(strings.NewReader(strings.Repeat("x", 1<<20))))
+	assert.Len(t, readRequestBody(req, recorder.maxBodyBytes+1), 101)
+
+	res, err := cl.DoRequestRaw(context.Background(), req)
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, 1<<20, sent)
+
+	b, _ := recorder.HAR("")
+	var doc harDocument
+	assert.NoError(t, json.Unmarshal(b, &doc))
+	assert.Equal(t, strings.Repeat("x", 100)+"...[truncated]", doc.Log.Entries[0].Request.PostData.Text)
+}
+
+func TestThatTheLeastRecentlyRecordedFlowsAreDiscarded(t *testing.T) {
+	recorder := NewHARRecorder(HAROptions{MaxFlows: 2})
+
+	recorder.add("flow-1", harEntry{})
+	recorder.add("flow-2", harEntry{})
+	recorder.add("flow-1", harEntry{})
+	recorder.add("flow-3", harEntry{})
+
+	assert.ElementsMatch(t, []string{"flow-1", "flow-3"}, recorder.FlowIDs())
+}
@@ -0,0 +1,235 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type (
+	// Request is a fluent builder for a single call. Every option composes
+	// with the others, and the client's authorization, flow-id and trace
+	// headers are always set.
+	Request struct {
+		client  *Client
+		ctx     context.Context
+		method  string
+		url     string
+		query   url.Values
+		header  http.Header
+		body    io.Reader
+		timeout time.Duration
+		err     error
+		// detached requests are not bound to ctx, which is only used for
+		// the recording, as the DoRequest* wrappers always did.
+		detached bool
+	}
+
+	cancelOnClose struct {
+		io.ReadCloser
+		cancel context.CancelFunc
+	}
+)
+
+// R starts building a request bound to ctx. A nil ctx is taken as
+// context.Background().
+func (c *Client) R(ctx context.Context) *Request {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return &Request{
+		client: c,
+		ctx:    ctx,
+		method: http.MethodGet,
+		query:  url.Values{},
+		header: http.Header{},
+	}
+}
+
+func (r *Request) Method(method string) *Request {
+	r.method = method
+	return r
+}
+
+func (r *Request) URL(url string) *Request {
+	r.url = url
+	return r
+}
+
+// Query adds a query parameter to the ones already present in the URL.
+func (r *Request) Query(key, value string) *Request {
+	r.query.Add(key, value)
+	return r
+}
+
+// Header sets a header, overriding the generic ones if needed.
+func (r *Request) Header(name, value string) *Request {
+	r.header.Set(name, value)
+	return r
+}
+
+func (r *Request) Headers(headers map[string]string) *Request {
+	for name, value := range headers {
+		r.header.Set(name, value)
+	}
+
+	return r
+}
+
+func (r *Request) ContentType(contentType string) *Request {
+	if contentType != "" {
+		r.header.Set("Content-Type", contentType)
+	}
+
+	return r
+}
+
+func (r *Request) Body(body io.Reader) *Request {
+	r.body = body
+	return r
+}
+
+// JSONBody marshals v as the request body. Marshalling errors are returned
+// when the request is sent.
+func (r *Request) JSONBody(v interface{}) *Request {
+	b, err := json.Marshal(v)
+	if err != nil {
+		r.err = err
+		return r
+	}
+
+	r.body = bytes.NewReader(b)
+
+	return r.ContentType(ContentTypeJSON)
+}
+
+// Timeout bounds the whole call, including reading the response body.
+func (r *Request) Timeout(d time.Duration) *Request {
+	r.timeout = d
+	return r
+}
+
+// Build returns the *http.Request without sending it.
+func (r *Request) Build() (*http.Request, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	u := r.url
+	if len(r.query) != 0 {
+		parsed, err := url.Parse(r.url)
+		if err != nil {
+			return nil, err
+		}
+
+		q := parsed.Query()
+		for key, values := range r.query {
+			for _, value := range values {
+				q.Add(key, value)
+			}
+		}
+		parsed.RawQuery = q.Encode()
+		u = parsed.String()
+	}
+
+	request, err := r.client.setGenericHeaders(r.method, u, r.body, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if !r.detached {
+		request = request.WithContext(r.ctx)
+		SetTraceparentFromContext(r.ctx, request)
+	}
+
+	for name, values := range r.header {
+		request.Header[name] = values
+	}
+
+	return request, nil
+}
+
+// Do sends the request and returns the body, failing on non 2xx status.
+func (r *Request) Do() ([]byte, error) {
+	_, body, err := r.do()
+
+	return body, err
+}
+
+// Decode sends the request advertising the registered codecs in Accept and
+// decodes the response into v.
+func (r *Request) Decode(v interface{}) error {
+	if r.header.Get("Accept") == "" {
+		r.header.Set("Accept", r.client.accept())
+	}
+
+	res, body, err := r.do()
+	if err != nil {
+		return err
+	}
+
+	return r.client.decode(res.Header.Get("Content-Type"), body, v)
+}
+
+// DoRaw sends the request and hands back the response as is, without
+// checking the status. The caller must close the body.
+func (r *Request) DoRaw() (*http.Response, error) {
+	request, err := r.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.context()
+	res, err := r.client.DoRequestRaw(ctx, request.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return res, err
+	}
+
+	res.Body = &cancelOnClose{ReadCloser: res.Body, cancel: cancel}
+
+	return res, nil
+}
+
+func (r *Request) do() (*http.Response, []byte, error) {
+	request, err := r.Build()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := r.context()
+	defer cancel()
+
+	return r.client.do(r.ctx, request.WithContext(ctx))
+}
+
+func (r *Request) context() (context.Context, context.CancelFunc) {
+	parent := r.ctx
+	if r.detached {
+		parent = context.Background()
+	}
+
+	if r.timeout > 0 {
+		return context.WithTimeout(parent, r.timeout)
+	}
+
+	return context.WithCancel(parent)
+}
+
+// detach keeps the request from being canceled by ctx or taking its trace.
+func (r *Request) detach() *Request {
+	r.detached = true
+	return r
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+
+	return err
+}
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestThatBuilderComposesAllOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"method":        r.Method,
+			"query":         r.URL.RawQuery,
+			"authorization": r.Header.Get("Authorization"),
+			"contentType":   r.Header.Get("Content-Type"),
+			"custom":        r.Header.Get("X-Custom"),
+			"name":          body["name"],
+		})
+	}))
+	defer server.Close()
+
+	var out map[string]string
+	err := NewClientWithTokent(server.Client(), "myToken").
+		R(context.Background()).
+		Method("POST").
+		URL(server.URL+"?a=1").
+		Query("b", "2").
+		Header("X-Custom", "yes").
+		JSONBody(map[string]string{"name": "bob"}).
+		Timeout(time.Second).
+		Decode(&out)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"method":        "POST",
+		"query":         "a=1&b=2",
+		"authorization": "Bearer myToken",
+		"contentType":   "application/json",
+		"custom":        "yes",
+		"name":          "bob",
+	}, out)
+}
+
+func TestThatBuilderTimeoutCancelsTheCall(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	_, err := NewClient(server.Client()).R(context.Background()).URL(server.URL).Timeout(10 * time.Millisecond).Do()
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestThatDoRawSkipsStatusCheckButSetsAuthorization(t *testing.T) {
+	httpClientMock := newHTTPClientMock()
+	httpClientMock.checkToken = true
+	httpClientMock.status = 500
+
+	res, err := NewClientWithTokent(httpClientMock, "myToken").R(context.Background()).URL("http://example.com").DoRaw()
+	assert.NoError(t, err)
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, 500, res.StatusCode)
+	assert.Equal(t, "Hello, client", string(body))
+}
+
+func TestThatJSONBodyErrorsAreReturnedOnSend(t *testing.T) {
+	_, err := NewClient(newHTTPClientMock()).R(context.Background()).URL("http://example.com").JSONBody(make(chan int)).Do()
+
+	assert.Error(t, err)
+}
+
+func TestThatWrappersKeepIgnoringTheContext(t *testing.T) {
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	cl := NewClient(server.Client())
+
+	//lint:ignore SA1012 callers used to pass a nil context
+	body, err := cl.DoRequest(nil, "GET", server.URL, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", string(body))
+
+	tid, _ := trace.TraceIDFromHex("0af7651916cd43dd8448eb211c80319c")
+	sid, _ := trace.SpanIDFromHex("b7ad6b7169203331")
+	ctx, cancel := context.WithCancel(trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: tid,
+		SpanID:  sid,
+	})))
+	cancel()
+
+	_, err = cl.DoRequestWithContentType(ctx, "GET", server.URL, nil, "text/plain")
+	assert.NoError(t, err)
+	assert.Empty(t, got.Header.Get("proper-referer"))
+
+	_, err = cl.R(ctx).URL(server.URL).Do()
+	assert.ErrorIs(t, err, context.Canceled)
+}
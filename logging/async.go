@@ -0,0 +1,170 @@
+package logging
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+const defaultAsyncQueueSize = 1024
+
+const (
+	// DropWhenFull discards entries while the queue is full.
+	DropWhenFull OverflowPolicy = iota
+	// BlockWhenFull makes writers wait for room in the queue.
+	BlockWhenFull
+)
+
+var ErrWriterClosed = errors.New("logging: writer closed")
+
+type (
+	OverflowPolicy int
+
+	AsyncOptions struct {
+		// QueueSize bounds the number of pending entries. Defaults to 1024.
+		QueueSize int
+		Policy    OverflowPolicy
+	}
+
+	// AsyncWriter writes to an underlying writer from a background
+	// goroutine, so slow sinks don't hold the logging callers. Call Close
+	// on shutdown to write the pending entries.
+	AsyncWriter struct {
+		w       io.Writer
+		policy  OverflowPolicy
+		queue   chan asyncItem
+		done    chan struct{}
+		dropped atomic.Uint64
+
+		mu     sync.RWMutex
+		closed bool
+
+		errMu sync.Mutex
+		err   error
+	}
+
+	asyncItem struct {
+		p     []byte
+		flush chan struct{}
+	}
+)
+
+func NewAsyncWriter(w io.Writer, opts AsyncOptions) *AsyncWriter {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultAsyncQueueSize
+	}
+
+	a := &AsyncWriter{
+		w:      w,
+		policy: opts.Policy,
+		queue:  make(chan asyncItem, opts.QueueSize),
+		done:   make(chan struct{}),
+	}
+
+	go a.run()
+
+	return a
+}
+
+// Write queues a copy of p. With DropWhenFull it never blocks, and counts
+// the entries it had to discard.
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.closed {
+		return 0, ErrWriterClosed
+	}
+
+	// Handlers reuse their buffers once Write returns.
+	item := asyncItem{p: append([]byte{}, p...)}
+
+	if a.policy == BlockWhenFull {
+		a.queue <- item
+		return len(p), nil
+	}
+
+	select {
+	case a.queue <- item:
+	default:
+		a.dropped.Add(1)
+	}
+
+	return len(p), nil
+}
+
+// Dropped returns the number of entries discarded because the queue was full.
+func (a *AsyncWriter) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+// Flush waits until the entries queued before the call are written, and
+// returns the last write error if any.
+func (a *AsyncWriter) Flush() error {
+	a.mu.RLock()
+	if a.closed {
+		a.mu.RUnlock()
+		return a.lastErr()
+	}
+
+	flushed := make(chan struct{})
+	a.queue <- asyncItem{flush: flushed}
+	a.mu.RUnlock()
+
+	<-flushed
+
+	return a.lastErr()
+}
+
+// Close writes the pending entries and stops the background goroutine. The
+// underlying writer is left open.
+func (a *AsyncWriter) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return a.lastErr()
+	}
+
+	a.closed = true
+	close(a.queue)
+	a.mu.Unlock()
+
+	<-a.done
+
+	return a.lastErr()
+}
+
+func (a *AsyncWriter) run() {
+	defer close(a.done)
+
+	for item := range a.queue {
+		if item.flush != nil {
+			if s, ok := a.w.(interface{ Sync() error }); ok {
+				a.setErr(s.Sync())
+			}
+			close(item.flush)
+			continue
+		}
+
+		_, err := a.w.Write(item.p)
+		a.setErr(err)
+	}
+}
+
+func (a *AsyncWriter) setErr(err error) {
+	if err == nil {
+		return
+	}
+
+	a.errMu.Lock()
+	a.err = err
+	a.errMu.Unlock()
+}
+
+func (a *AsyncWriter) lastErr() error {
+	a.errMu.Lock()
+	defer a.errMu.Unlock()
+
+	return a.err
+}
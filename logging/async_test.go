@@ -0,0 +1,69 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type slowWriter struct {
+	mu      sync.Mutex
+	release chan struct{}
+	b       bytes.Buffer
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	<-w.release
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.b.Write(p)
+}
+
+func (w *slowWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.b.String()
+}
+
+func TestThatAsyncWriterWritesEverythingOnClose(t *testing.T) {
+	b := &bytes.Buffer{}
+	w := NewAsyncWriter(b, AsyncOptions{Policy: BlockWhenFull, QueueSize: 2})
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(w))
+
+	for i := 0; i < 10; i++ {
+		Infof(ctx, "page %d", i)
+	}
+
+	assert.NoError(t, w.Close())
+	assert.Equal(t, 10, strings.Count(b.String(), `"message":"page`))
+	assert.Zero(t, w.Dropped())
+
+	_, err := w.Write([]byte("late"))
+	assert.ErrorIs(t, err, ErrWriterClosed)
+}
+
+func TestThatAsyncWriterDropsWhenQueueIsFull(t *testing.T) {
+	sw := &slowWriter{release: make(chan struct{})}
+	w := NewAsyncWriter(sw, AsyncOptions{QueueSize: 1})
+
+	for i := 0; i < 5; i++ {
+		n, err := w.Write([]byte("entry\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, 6, n)
+	}
+
+	close(sw.release)
+	assert.NoError(t, w.Flush())
+
+	written := strings.Count(sw.String(), "entry")
+	assert.Greater(t, w.Dropped(), uint64(0))
+	assert.Equal(t, uint64(5), uint64(written)+w.Dropped())
+	assert.NoError(t, w.Close())
+}
@@ -0,0 +1,92 @@
+package logging
+
+import (
+	"log/slog"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// packagePrefix is the prefix of the functions of this package, used to skip
+// our own frames when looking for the caller.
+var packagePrefix = reflect.TypeOf(report{}).PkgPath() + "."
+
+// callerFrames returns the stack of the calling goroutine, starting at the
+// first frame outside of this package.
+func callerFrames() []runtime.Frame {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(2, pcs)
+
+	var (
+		frames  []runtime.Frame
+		skipped bool
+		it      = runtime.CallersFrames(pcs[:n])
+	)
+	for {
+		frame, more := it.Next()
+		if skipped || !isOwnFrame(frame) {
+			skipped = true
+			frames = append(frames, frame)
+		}
+
+		if !more {
+			break
+		}
+	}
+
+	return frames
+}
+
+func isOwnFrame(frame runtime.Frame) bool {
+	return strings.HasPrefix(frame.Function, packagePrefix) && !strings.HasSuffix(frame.File, "_test.go")
+}
+
+// callerPC returns the program counter of the first caller outside of this
+// package, suitable for slog.Record.PC.
+func callerPC() uintptr {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+
+	for i := 0; i < n; i++ {
+		// A single pc may stand for several inlined frames.
+		it := runtime.CallersFrames(pcs[i : i+1])
+		for {
+			frame, more := it.Next()
+			if !isOwnFrame(frame) {
+				return pcs[i]
+			}
+
+			if !more {
+				break
+			}
+		}
+	}
+
+	return 0
+}
+
+// sourceFrame resolves pc to the first frame outside of this package, as our
+// helpers may be inlined in the caller.
+func sourceFrame(pc uintptr) runtime.Frame {
+	it := runtime.CallersFrames([]uintptr{pc})
+	for {
+		frame, more := it.Next()
+		if !isOwnFrame(frame) || !more {
+			return frame
+		}
+	}
+}
+
+// sourceLocationAttr returns the caller at pc in the shape expected by Cloud
+// Logging, where the line is a string.
+// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#LogEntrySourceLocation
+func sourceLocationAttr(pc uintptr) slog.Attr {
+	frame := sourceFrame(pc)
+
+	return slog.Group("logging.googleapis.com/sourceLocation",
+		slog.String("file", frame.File),
+		slog.String("line", strconv.Itoa(frame.Line)),
+		slog.String("function", frame.Function),
+	)
+}
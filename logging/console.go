@@ -0,0 +1,285 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/propertechnologies/monitor/context_util"
+)
+
+const formatEnv = "LOG_FORMAT"
+
+const (
+	// FormatAuto writes JSON, except for contexts whose env is "local".
+	FormatAuto Format = iota
+	FormatJSON
+	FormatConsole
+)
+
+const (
+	colorReset  = "\033[0m"
+	colorGray   = "\033[90m"
+	colorRed    = "\033[31m"
+	colorYellow = "\033[33m"
+	colorBlue   = "\033[34m"
+	colorCyan   = "\033[36m"
+)
+
+// consoleSkippedKeys are only useful to Cloud Logging.
+var consoleSkippedKeys = map[string]bool{
+	"@type":                                 true,
+	"serviceContext":                        true,
+	"context":                               true,
+	"logging.googleapis.com/spanId":         true,
+	"logging.googleapis.com/trace_sampled":  true,
+	"logging.googleapis.com/sourceLocation": true,
+	"logging.googleapis.com/labels":         true,
+	"logging.googleapis.com/operation":      true,
+}
+
+type (
+	// Format selects how entries are rendered.
+	Format int
+
+	// consoleHandler renders an entry as one human readable line, followed
+	// by its stack trace if any.
+	consoleHandler struct {
+		mu     *sync.Mutex
+		w      io.Writer
+		colors bool
+		attrs  []slog.Attr
+	}
+
+	// formatHandler picks the JSON or the console handler for each record.
+	formatHandler struct {
+		json    slog.Handler
+		console slog.Handler
+		format  Format
+	}
+)
+
+// WithFormat forces the output format. It overrides LOG_FORMAT.
+func WithFormat(f Format) Option {
+	return func(o *options) {
+		o.format = f
+	}
+}
+
+func formatFromEnv() Format {
+	switch strings.ToLower(os.Getenv(formatEnv)) {
+	case "json":
+		return FormatJSON
+	case "console", "text":
+		return FormatConsole
+	}
+
+	return FormatAuto
+}
+
+func newConsoleHandler(w io.Writer) *consoleHandler {
+	return &consoleHandler{mu: &sync.Mutex{}, w: w, colors: useColors(w)}
+}
+
+// useColors tells whether w is a terminal, unless NO_COLOR is set.
+func useColors(w io.Writer) bool {
+	if _, noColor := os.LookupEnv("NO_COLOR"); noColor {
+		return false
+	}
+
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (f *formatHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return f.pick(ctx).Enabled(ctx, level)
+}
+
+func (f *formatHandler) Handle(ctx context.Context, r slog.Record) error {
+	return f.pick(ctx).Handle(ctx, r)
+}
+
+func (f *formatHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &formatHandler{json: f.json.WithAttrs(attrs), console: f.console.WithAttrs(attrs), format: f.format}
+}
+
+func (f *formatHandler) WithGroup(name string) slog.Handler {
+	return &formatHandler{json: f.json.WithGroup(name), console: f.console.WithGroup(name), format: f.format}
+}
+
+func (f *formatHandler) pick(ctx context.Context) slog.Handler {
+	switch f.format {
+	case FormatJSON:
+		return f.json
+	case FormatConsole:
+		return f.console
+	}
+
+	if context_util.GetEnv(ctx) == "local" {
+		return f.console
+	}
+
+	return f.json
+}
+
+func (c *consoleHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (c *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h := *c
+	h.attrs = append(append([]slog.Attr{}, c.attrs...), attrs...)
+
+	return &h
+}
+
+// WithGroup is not supported, groups are flattened by the span context
+// handler before reaching the console.
+func (c *consoleHandler) WithGroup(string) slog.Handler {
+	return c
+}
+
+func (c *consoleHandler) Handle(_ context.Context, r slog.Record) error {
+	var (
+		b                  bytes.Buffer
+		app, flowID, trace string
+		stack              string
+		fields             []slog.Attr
+	)
+
+	collect := func(a slog.Attr) bool {
+		v := a.Value.Resolve()
+
+		switch {
+		case a.Key == "app":
+			app = v.String()
+		case a.Key == "flow-id":
+			flowID = v.String()
+		case a.Key == "logging.googleapis.com/trace":
+			trace = v.String()
+			trace = trace[strings.LastIndex(trace, "/")+1:]
+			if len(trace) > 8 {
+				trace = trace[:8]
+			}
+		case a.Key == "stack_trace":
+			stack = v.String()
+		case consoleSkippedKeys[a.Key]:
+		case v.Kind() == slog.KindString && v.String() == "":
+		default:
+			fields = append(fields, slog.Attr{Key: a.Key, Value: v})
+		}
+
+		return true
+	}
+
+	for _, a := range c.attrs {
+		collect(a)
+	}
+	r.Attrs(collect)
+
+	b.WriteString(c.paint(colorGray, r.Time.Format("15:04:05.000")))
+	b.WriteByte(' ')
+	b.WriteString(c.paint(levelColor(r.Level), fmt.Sprintf("%-7s", severity(r.Level))))
+	b.WriteByte(' ')
+
+	var tags []string
+	if app != "" {
+		tags = append(tags, app)
+	}
+	if flowID != "" {
+		tags = append(tags, "flow="+flowID)
+	}
+	if trace != "" {
+		tags = append(tags, "trace="+trace)
+	}
+	if len(tags) != 0 {
+		b.WriteString(c.paint(colorCyan, "["+strings.Join(tags, " ")+"]"))
+		b.WriteByte(' ')
+	}
+
+	b.WriteString(r.Message)
+
+	for _, a := range fields {
+		b.WriteByte(' ')
+		writeConsoleAttr(&b, c, "", a)
+	}
+	b.WriteByte('\n')
+
+	if stack != "" {
+		for _, line := range strings.Split(strings.TrimRight(stack, "\n"), "\n") {
+			b.WriteString(c.paint(colorGray, "    "+line))
+			b.WriteByte('\n')
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, err := c.w.Write(b.Bytes())
+
+	return err
+}
+
+func writeConsoleAttr(b *bytes.Buffer, c *consoleHandler, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	key := prefix + a.Key
+
+	if v.Kind() == slog.KindGroup {
+		for i, g := range v.Group() {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			writeConsoleAttr(b, c, key+".", g)
+		}
+		return
+	}
+
+	b.WriteString(c.paint(colorBlue, key+"="))
+
+	s := v.String()
+	if strings.ContainsAny(s, " \t\n\"") {
+		s = fmt.Sprintf("%q", s)
+	}
+	b.WriteString(s)
+}
+
+func (c *consoleHandler) paint(color, s string) string {
+	if !c.colors {
+		return s
+	}
+
+	return color + s + colorReset
+}
+
+func levelColor(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return colorRed
+	case level >= slog.LevelWarn:
+		return colorYellow
+	case level >= slog.LevelInfo:
+		return colorBlue
+	}
+
+	return colorGray
+}
+
+// severity returns the Cloud Logging name of level.
+func severity(level slog.Level) string {
+	if level == slog.LevelWarn {
+		return "WARNING"
+	}
+
+	return level.String()
+}
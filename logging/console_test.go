@@ -0,0 +1,81 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/propertechnologies/monitor/context_util"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestThatLocalEnvUsesTheConsoleFormat(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	t.Setenv("LOG_FORMAT", "")
+
+	tid, _ := trace.TraceIDFromHex("0af7651916cd43dd8448eb211c80319c")
+	sid, _ := trace.SpanIDFromHex("b7ad6b7169203331")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: tid,
+		SpanID:  sid,
+	}))
+
+	b := &bytes.Buffer{}
+	ctx = SetLogger(ctx, NewLoggerWithWriter(b, WithProjectID("test")))
+	ctx = context.WithValue(ctx, "env", "local")
+	ctx = context.WithValue(ctx, "FlowID", "flow-1")
+	ctx = context_util.SetServiceName(ctx, "ledgerlord")
+
+	Warn(ctx, "retrying request", "account_id", "acc-1", "reason", "bank is down")
+
+	line := b.String()
+	assert.Contains(t, line, "WARNING [ledgerlord flow=flow-1 trace=0af76519] retrying request account_id=acc-1 reason=\"bank is down\"\n")
+	assert.NotContains(t, line, "{")
+}
+
+func TestThatConsoleRendersStackTracesOnSeparateLines(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b, WithFormat(FormatConsole)))
+
+	Reportf(ctx, "failed")
+
+	lines := strings.Split(b.String(), "\n")
+	assert.Contains(t, lines[0], "ERROR   failed")
+	assert.True(t, strings.HasPrefix(lines[1], "    goroutine "))
+}
+
+func TestThatOtherEnvsKeepTheJSONFormat(t *testing.T) {
+	t.Setenv("LOG_FORMAT", "")
+
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b))
+	ctx = context.WithValue(ctx, "env", "prod")
+
+	Infof(ctx, "hello")
+
+	assert.True(t, strings.HasPrefix(b.String(), "{"))
+}
+
+func TestThatColorsAreOnlyUsedOnTerminals(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	os.Unsetenv("NO_COLOR")
+	t.Setenv("LOG_FORMAT", "console")
+
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b))
+
+	Error(ctx, "failed")
+
+	assert.NotContains(t, b.String(), "\033[")
+
+	f, err := os.CreateTemp(t.TempDir(), "log")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	assert.False(t, useColors(f))
+}
@@ -0,0 +1,268 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	"github.com/propertechnologies/monitor/context_util"
+	"github.com/propertechnologies/monitor/properrors"
+)
+
+// ReportedErrorEventType is the @type of the entries sent to Error Reporting.
+const ReportedErrorEventType = "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent"
+
+var (
+	versionOnce    sync.Once
+	serviceVersion string
+)
+
+type (
+	// errorReport carries what Cloud Error Reporting needs to group an entry.
+	// https://cloud.google.com/error-reporting/docs/formatting-error-messages
+	errorReport struct {
+		stack  string
+		frames []runtime.Frame
+		// recorded is set when the error is already recorded on the span,
+		// as Recover does for panics.
+		recorded bool
+	}
+
+	// StackCarrier is implemented by errors that captured the program
+	// counters of the stack where they were created, e.g. with
+	// runtime.Callers. ReportError uses the deepest one of a chain.
+	StackCarrier interface {
+		Callers() []uintptr
+	}
+
+	leveler interface {
+		Level() slog.Level
+	}
+)
+
+// ReportError logs err and sends it to Error Reporting. It walks the wrapped
+// chain to add the properrors.Error identity as fields, and uses the stack
+// captured by the deepest StackCarrier when there is one. Cancellations are
+// logged as warnings without being reported, and errors may choose their
+// level by implementing Level() slog.Level.
+func ReportError(ctx context.Context, err error, args ...interface{}) {
+	if err == nil {
+		return
+	}
+
+	level := errorLevel(err)
+	if level >= slog.LevelError {
+		frames := callerFrames()
+		if pcs := deepestCallers(err); len(pcs) != 0 {
+			frames = framesOf(pcs)
+		}
+
+		ctx = context.WithValue(ctx, report{}, newErrorReport(frames))
+	}
+
+	logAttrs(ctx, level, err.Error(), append([]interface{}{errorAttr(err)}, args...)...)
+}
+
+func errorLevel(err error) slog.Level {
+	var l leveler
+	if errors.As(err, &l) {
+		return l.Level()
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return slog.LevelWarn
+	}
+
+	return slog.LevelError
+}
+
+// errorAttr describes err under the "error" field.
+func errorAttr(err error) slog.Attr {
+	var chain []string
+	walkErrors(err, 0, func(e error, _ int) {
+		chain = append(chain, fmt.Sprintf("%T", e))
+	})
+
+	attrs := []slog.Attr{
+		slog.String("message", err.Error()),
+		slog.Any("chain", chain),
+	}
+
+	var p *properrors.Error
+	if errors.As(err, &p) {
+		attrs = append(attrs,
+			slog.String("id", p.ID),
+			slog.String("reason", p.Err),
+			slog.String("description", p.Desc),
+		)
+	}
+
+	return slog.Attr{Key: "error", Value: slog.GroupValue(attrs...)}
+}
+
+func deepestCallers(err error) []uintptr {
+	var (
+		pcs   []uintptr
+		depth = -1
+	)
+	walkErrors(err, 0, func(e error, d int) {
+		if s, ok := e.(StackCarrier); ok && d > depth {
+			if c := s.Callers(); len(c) != 0 {
+				pcs, depth = c, d
+			}
+		}
+	})
+
+	return pcs
+}
+
+// walkErrors calls fn on err and the errors it wraps, depth first, following
+// both Unwrap() error and the Unwrap() []error of errors.Join and
+// fmt.Errorf with several %w.
+func walkErrors(err error, depth int, fn func(e error, depth int)) {
+	if err == nil {
+		return
+	}
+
+	fn(err, depth)
+
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		walkErrors(u.Unwrap(), depth+1, fn)
+	case interface{ Unwrap() []error }:
+		for _, e := range u.Unwrap() {
+			walkErrors(e, depth+1, fn)
+		}
+	}
+}
+
+func framesOf(pcs []uintptr) []runtime.Frame {
+	var frames []runtime.Frame
+
+	it := runtime.CallersFrames(pcs)
+	for {
+		frame, more := it.Next()
+		frames = append(frames, frame)
+
+		if !more {
+			break
+		}
+	}
+
+	return frames
+}
+
+func newErrorReport(frames []runtime.Frame) *errorReport {
+	return &errorReport{
+		stack:  formatStack(goroutineHeader(), frames),
+		frames: frames,
+	}
+}
+
+func (r *errorReport) attrs(ctx context.Context) []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("@type", ReportedErrorEventType),
+		slog.String("stack_trace", r.stack),
+	}
+
+	if service := reportedService(ctx); service != "" {
+		sc := []slog.Attr{slog.String("service", service)}
+		if version := buildVersion(); version != "" {
+			sc = append(sc, slog.String("version", version))
+		}
+		attrs = append(attrs, slog.Attr{Key: "serviceContext", Value: slog.GroupValue(sc...)})
+	}
+
+	if len(r.frames) != 0 {
+		f := r.frames[0]
+		attrs = append(attrs, slog.Group("context",
+			slog.Group("reportLocation",
+				slog.String("filePath", f.File),
+				slog.Int("lineNumber", f.Line),
+				slog.String("functionName", f.Function),
+			),
+		))
+	}
+
+	return attrs
+}
+
+func reportedService(ctx context.Context) string {
+	if service := context_util.GetServiceName(ctx); service != "" {
+		return service
+	}
+
+	// Set by Cloud Run.
+	return os.Getenv("K_SERVICE")
+}
+
+// buildVersion returns the main module version, or the VCS revision for
+// development builds.
+func buildVersion() string {
+	versionOnce.Do(func() {
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			return
+		}
+
+		if v := info.Main.Version; v != "" && v != "(devel)" {
+			serviceVersion = v
+			return
+		}
+
+		var revision, modified string
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				revision = s.Value
+			case "vcs.modified":
+				modified = s.Value
+			}
+		}
+
+		if len(revision) > 12 {
+			revision = revision[:12]
+		}
+		if revision != "" && modified == "true" {
+			revision += "-dirty"
+		}
+
+		serviceVersion = revision
+	})
+
+	return serviceVersion
+}
+
+// goroutineHeader returns the "goroutine N [running]:" line of the current
+// goroutine, as printed by runtime.Stack.
+func goroutineHeader() string {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+
+	if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+		return string(buf[:i])
+	}
+
+	return "goroutine 1 [running]:"
+}
+
+// formatStack renders frames the way runtime.Stack does, which is the format
+// Error Reporting parses for Go.
+func formatStack(header string, frames []runtime.Frame) string {
+	var b strings.Builder
+
+	b.WriteString(header)
+	b.WriteByte('\n')
+	for _, f := range frames {
+		fmt.Fprintf(&b, "%s(...)\n\t%s:%d\n", f.Function, f.File, f.Line)
+	}
+
+	return b.String()
+}
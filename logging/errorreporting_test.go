@@ -0,0 +1,140 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/propertechnologies/monitor/context_util"
+	"github.com/propertechnologies/monitor/properrors"
+	"github.com/stretchr/testify/assert"
+)
+
+type reportedEntry struct {
+	StackTrace     string `json:"stack_trace"`
+	ServiceContext struct {
+		Service string `json:"service"`
+		Version string `json:"version"`
+	} `json:"serviceContext"`
+	Context struct {
+		ReportLocation struct {
+			FilePath     string `json:"filePath"`
+			LineNumber   int    `json:"lineNumber"`
+			FunctionName string `json:"functionName"`
+		} `json:"reportLocation"`
+	} `json:"context"`
+}
+
+func TestThatReportfStackStartsAtTheCaller(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b))
+	ctx = context_util.SetServiceName(ctx, "ledgerlord")
+
+	Reportf(ctx, "failed to sync %s", "acc-1")
+
+	var entry reportedEntry
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &entry))
+
+	lines := strings.Split(entry.StackTrace, "\n")
+	assert.True(t, strings.HasPrefix(lines[0], "goroutine "))
+	assert.Contains(t, lines[1], "TestThatReportfStackStartsAtTheCaller")
+	assert.NotContains(t, entry.StackTrace, "logging.Reportf")
+
+	assert.Equal(t, "ledgerlord", entry.ServiceContext.Service)
+	assert.True(t, strings.HasSuffix(entry.Context.ReportLocation.FilePath, "errorreporting_test.go"))
+	assert.NotZero(t, entry.Context.ReportLocation.LineNumber)
+	assert.Contains(t, entry.Context.ReportLocation.FunctionName, "TestThatReportfStackStartsAtTheCaller")
+}
+
+type stackError struct {
+	pcs []uintptr
+}
+
+func (s *stackError) Error() string {
+	return "with stack"
+}
+
+func (s *stackError) Callers() []uintptr {
+	return s.pcs
+}
+
+func newStackError() error {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(1, pcs)
+
+	return &stackError{pcs: pcs[:n]}
+}
+
+func TestThatReportErrorEmitsProperErrorFields(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b))
+
+	err := fmt.Errorf("syncing account: %w", properrors.New("0042", "Bank unavailable").Wrap(errors.New("timeout")))
+	ReportError(ctx, err, "account_id", "acc-1")
+
+	var entry struct {
+		Severity string `json:"severity"`
+		Message  string `json:"message"`
+		Type     string `json:"@type"`
+		Account  string `json:"account_id"`
+		Error    struct {
+			ID          string   `json:"id"`
+			Reason      string   `json:"reason"`
+			Description string   `json:"description"`
+			Chain       []string `json:"chain"`
+		} `json:"error"`
+	}
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &entry))
+	assert.Equal(t, "ERROR", entry.Severity)
+	assert.Equal(t, err.Error(), entry.Message)
+	assert.Equal(t, ReportedErrorEventType, entry.Type)
+	assert.Equal(t, "acc-1", entry.Account)
+	assert.Equal(t, "0042", entry.Error.ID)
+	assert.Equal(t, "Bank unavailable", entry.Error.Reason)
+	assert.Equal(t, "https://ledgerlord.proper.ai/errors/0042", entry.Error.Description)
+	assert.Equal(t, []string{"*fmt.wrapError", "*properrors.Error", "*errors.errorString"}, entry.Error.Chain)
+}
+
+func TestThatReportErrorUsesTheStackOfTheDeepestError(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b))
+
+	ReportError(ctx, fmt.Errorf("wrapped: %w", newStackError()))
+
+	var entry reportedEntry
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &entry))
+	assert.Contains(t, entry.Context.ReportLocation.FunctionName, "newStackError")
+}
+
+func TestThatJoinedErrorsAreWalked(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b))
+
+	err := fmt.Errorf("sync: %w and %w", errors.New("first"), errors.Join(errors.New("second"), newStackError()))
+	ReportError(ctx, err)
+
+	var entry struct {
+		reportedEntry
+		Error struct {
+			Chain []string `json:"chain"`
+		} `json:"error"`
+	}
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &entry))
+	assert.Equal(t, []string{"*fmt.wrapErrors", "*errors.errorString", "*errors.joinError", "*errors.errorString", "*logging.stackError"}, entry.Error.Chain)
+	assert.Contains(t, entry.Context.ReportLocation.FunctionName, "newStackError")
+}
+
+func TestThatCancellationsAreWarningsAndNotReported(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b))
+
+	ReportError(ctx, fmt.Errorf("fetching: %w", context.Canceled))
+
+	assert.Contains(t, b.String(), `"severity":"WARNING"`)
+	assert.NotContains(t, b.String(), ReportedErrorEventType)
+}
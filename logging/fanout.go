@@ -0,0 +1,108 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+)
+
+type (
+	// Sink is a destination of a fan-out handler. Records below Level are not
+	// sent to it; a nil Level accepts everything.
+	Sink struct {
+		Handler slog.Handler
+		Level   slog.Leveler
+	}
+
+	fanoutHandler struct {
+		sinks []Sink
+	}
+
+	// leveledHandler drops the records below the logger's level, for the
+	// outputs next to sinks of lower levels.
+	leveledHandler struct {
+		slog.Handler
+		level slog.Leveler
+	}
+
+	writerSink struct {
+		writer io.Writer
+		level  slog.Leveler
+	}
+)
+
+// WithSink also writes the entries at or above level to w, e.g. a file of
+// the bot's artifact bundle. level may be below the logger's level, and a
+// nil level follows it. Entries are written in JSON unless WithFormat or
+// LOG_FORMAT force a format. It can be given several times.
+func WithSink(w io.Writer, level slog.Leveler) Option {
+	return func(o *options) {
+		o.sinks = append(o.sinks, writerSink{writer: w, level: level})
+	}
+}
+
+// NewFanoutHandler returns a handler writing each record to every sink whose
+// level accepts it.
+func NewFanoutHandler(sinks ...Sink) slog.Handler {
+	return &fanoutHandler{sinks: sinks}
+}
+
+func (f *fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, s := range f.sinks {
+		if s.accepts(level) && s.Handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (f *fanoutHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, s := range f.sinks {
+		if !s.accepts(r.Level) || !s.Handler.Enabled(ctx, r.Level) {
+			continue
+		}
+
+		if err := s.Handler.Handle(ctx, r.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (f *fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	sinks := make([]Sink, 0, len(f.sinks))
+	for _, s := range f.sinks {
+		sinks = append(sinks, Sink{Handler: s.Handler.WithAttrs(attrs), Level: s.Level})
+	}
+
+	return &fanoutHandler{sinks: sinks}
+}
+
+func (f *fanoutHandler) WithGroup(name string) slog.Handler {
+	sinks := make([]Sink, 0, len(f.sinks))
+	for _, s := range f.sinks {
+		sinks = append(sinks, Sink{Handler: s.Handler.WithGroup(name), Level: s.Level})
+	}
+
+	return &fanoutHandler{sinks: sinks}
+}
+
+func (s Sink) accepts(level slog.Level) bool {
+	return s.Level == nil || level >= s.Level.Level()
+}
+
+func (l *leveledHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return levelEnabled(ctx, l.level, level) && l.Handler.Enabled(ctx, level)
+}
+
+func (l *leveledHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &leveledHandler{Handler: l.Handler.WithAttrs(attrs), level: l.level}
+}
+
+func (l *leveledHandler) WithGroup(name string) slog.Handler {
+	return &leveledHandler{Handler: l.Handler.WithGroup(name), level: l.level}
+}
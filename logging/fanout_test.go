@@ -0,0 +1,70 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThatSinksOnlyGetEntriesAtTheirLevel(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "debug")
+
+	stdout, errs := &bytes.Buffer{}, &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(stdout, WithSink(errs, slog.LevelError)))
+
+	Debugf(ctx, "fetching page")
+	Errorf(ctx, "login failed")
+
+	assert.Contains(t, stdout.String(), "fetching page")
+	assert.Contains(t, stdout.String(), "login failed")
+	assert.NotContains(t, errs.String(), "fetching page")
+	assert.Contains(t, errs.String(), `"message":"login failed"`)
+	assert.Contains(t, errs.String(), `"severity":"ERROR"`)
+}
+
+func TestThatFanoutHandlerKeepsAttrsAndGroupsOfEverySink(t *testing.T) {
+	a, b := &bytes.Buffer{}, &bytes.Buffer{}
+	logger := slog.New(NewFanoutHandler(
+		Sink{Handler: slog.NewJSONHandler(a, nil)},
+		Sink{Handler: slog.NewJSONHandler(b, nil), Level: slog.LevelWarn},
+	)).With("bot", "payroll").WithGroup("account")
+
+	logger.Info("synced", "id", 1)
+	logger.Warn("stale", "id", 2)
+
+	assert.Equal(t, 2, strings.Count(a.String(), `"bot":"payroll","account":{"id"`))
+	assert.NotContains(t, b.String(), "synced")
+	assert.Contains(t, b.String(), `"bot":"payroll","account":{"id":2}`)
+}
+
+func TestThatSinksCanBeMoreVerboseThanTheLogger(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "warn")
+
+	stdout, debug := &bytes.Buffer{}, &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(stdout, WithSink(debug, slog.LevelDebug)))
+
+	Debugf(ctx, "fetching page")
+	Warnf(ctx, "slow bank")
+
+	assert.NotContains(t, stdout.String(), "fetching page")
+	assert.Contains(t, stdout.String(), "slow bank")
+	assert.Contains(t, debug.String(), `"message":"fetching page"`)
+	assert.Contains(t, debug.String(), `"message":"slow bank"`)
+}
+
+func TestThatSinksAreWrittenInJSONLocally(t *testing.T) {
+	t.Setenv("LOG_FORMAT", "")
+
+	stdout, file := &bytes.Buffer{}, &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(stdout, WithSink(file, nil)))
+	ctx = context.WithValue(ctx, "env", "local")
+
+	Warnf(ctx, "slow bank")
+
+	assert.NotContains(t, stdout.String(), "{")
+	assert.Contains(t, file.String(), `"message":"slow bank"`)
+}
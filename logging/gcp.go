@@ -6,76 +6,344 @@ import (
 	"io"
 	"log/slog"
 	"os"
+	"time"
 
 	"github.com/propertechnologies/monitor/context_util"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
 
 type (
 	GCPLoggerWrapper struct {
 		logger *slog.Logger
+		// callers tells whether the caller of the log calls is needed, for
+		// the source location or the sampling.
+		callers bool
+		// sampling is shared by the loggers derived with With, nil when
+		// sampling is disabled.
+		sampling *samplingState
 	}
 )
 
-func NewLogger() *GCPLoggerWrapper {
-	return NewLoggerWithWriter(os.Stdout)
+func NewLogger(opts ...Option) *GCPLoggerWrapper {
+	return NewLoggerWithWriter(os.Stdout, opts...)
 }
 
-func NewLoggerWithWriter(writer io.Writer) *GCPLoggerWrapper {
-	// Use json as our base logging format.
-	jsonHandler := slog.NewJSONHandler(writer, &slog.HandlerOptions{ReplaceAttr: replacer})
+// NewLoggerWithWriter returns a logger writing to writer only. It doesn't
+// touch the global slog default, see SetDefault.
+func NewLoggerWithWriter(writer io.Writer, opts ...Option) *GCPLoggerWrapper {
+	o := newOptions(opts)
+	if o.projectID == "" {
+		startProjectDetection()
+	}
+
 	// Add span context attributes when Context is passed to logging calls.
-	instrumentedHandler := handlerWithSpanContext(jsonHandler)
-	// Set this handler as the global slog handler.
-	var l = slog.New(instrumentedHandler)
-	slog.SetDefault(l)
+	var handler slog.Handler = handlerWithSpanContext(newOutputs(writer, o), o)
+
+	var sampling *samplingState
+	if o.sampling != nil {
+		s := NewSamplingHandler(handler, *o.sampling).(*samplingHandler)
+		handler, sampling = s, s.state
+	}
 
 	return &GCPLoggerWrapper{
-		logger: l,
+		logger:   slog.New(handler),
+		callers:  o.sourceLocation || o.sampling != nil,
+		sampling: sampling,
+	}
+}
+
+// newOutputs returns the handler writing the entries to writer and the
+// sinks, and emitting them through OpenTelemetry when enabled.
+func newOutputs(writer io.Writer, o *options) slog.Handler {
+	var outputs []Sink
+
+	if o.otel == nil || !o.otel.Only {
+		var output slog.Handler = newOutputHandler(writer, o.format)
+		if len(o.sinks) != 0 {
+			// The logger lets through what any sink accepts, so the writer
+			// is kept at the logger's level.
+			sinks := []Sink{{Handler: &leveledHandler{Handler: output, level: o.level}}}
+			for _, s := range o.sinks {
+				// Sinks are files or collectors, written in JSON unless a
+				// format is forced.
+				format := o.format
+				if format == FormatAuto {
+					format = FormatJSON
+				}
+
+				var sink slog.Handler = newOutputHandler(s.writer, format)
+				if s.level == nil {
+					sink = &leveledHandler{Handler: sink, level: o.level}
+				}
+				sinks = append(sinks, Sink{Handler: sink, Level: s.level})
+			}
+			output = NewFanoutHandler(sinks...)
+		}
+
+		if o.entryLimit != nil {
+			output = newEntryLimitHandler(output, *o.entryLimit)
+		}
+
+		outputs = append(outputs, Sink{Handler: output})
 	}
+
+	if o.otel != nil {
+		var otel slog.Handler = newOTelHandler(*o.otel)
+		if len(o.sinks) != 0 {
+			otel = &leveledHandler{Handler: otel, level: o.level}
+		}
+		outputs = append(outputs, Sink{Handler: otel})
+	}
+
+	if len(outputs) == 1 {
+		return outputs[0].Handler
+	}
+
+	return NewFanoutHandler(outputs...)
+}
+
+// newOutputHandler renders the entries written to writer.
+func newOutputHandler(writer io.Writer, format Format) slog.Handler {
+	// Use json as our base logging format. Levels are filtered by the
+	// instrumented handler, so let everything through here.
+	jsonHandler := slog.NewJSONHandler(writer, &slog.HandlerOptions{ReplaceAttr: replacer, Level: slog.LevelDebug})
+
+	// Local runs get a human readable output instead.
+	return &formatHandler{json: jsonHandler, console: newConsoleHandler(writer), format: format}
+}
+
+// SetDefault installs the logger as the global slog default, so that plain
+// slog calls are written through it as well.
+func (g *GCPLoggerWrapper) SetDefault() *GCPLoggerWrapper {
+	slog.SetDefault(g.logger)
+	return g
+}
+
+func (g *GCPLoggerWrapper) Debugf(ctx context.Context, format string, args ...interface{}) {
+	g.log(g.withTemplate(ctx, format), slog.LevelDebug, fmt.Sprintf(format, args...), nil)
 }
 
 func (g *GCPLoggerWrapper) Infof(ctx context.Context, format string, args ...interface{}) {
-	slog.InfoContext(ctx, fmt.Sprintf(format, args...))
+	g.log(g.withTemplate(ctx, format), slog.LevelInfo, fmt.Sprintf(format, args...), nil)
 }
 
 func (g *GCPLoggerWrapper) Errorf(ctx context.Context, format string, args ...interface{}) {
-	slog.ErrorContext(ctx, fmt.Sprintf(format, args...))
+	g.log(g.withTemplate(ctx, format), slog.LevelError, fmt.Sprintf(format, args...), nil)
 }
 
 func (g *GCPLoggerWrapper) Warnf(ctx context.Context, format string, args ...interface{}) {
-	slog.WarnContext(ctx, fmt.Sprintf(format, args...))
+	g.log(g.withTemplate(ctx, format), slog.LevelWarn, fmt.Sprintf(format, args...), nil)
 }
 
-func handlerWithSpanContext(handler slog.Handler) *spanContextLogHandler {
-	return &spanContextLogHandler{Handler: handler}
+// Flush writes the summaries of the records suppressed by the sampling so
+// far. Call it on shutdown so they are not lost.
+func (g *GCPLoggerWrapper) Flush() {
+	if g.sampling != nil {
+		g.sampling.flush()
+	}
+}
+
+// withTemplate only keeps the format when it's used by the sampling.
+func (g *GCPLoggerWrapper) withTemplate(ctx context.Context, format string) context.Context {
+	if g.sampling == nil {
+		return ctx
+	}
+
+	return withTemplate(ctx, format)
+}
+
+// log builds the record itself so that its PC points to the caller of the
+// package helpers rather than to this wrapper.
+func (g *GCPLoggerWrapper) log(ctx context.Context, level slog.Level, msg string, args []interface{}) {
+	if !g.logger.Enabled(ctx, level) {
+		return
+	}
+
+	var pc uintptr
+	if g.callers {
+		pc = callerPC()
+	}
+
+	r := slog.NewRecord(time.Now(), level, msg, pc)
+	r.Add(args...)
+
+	_ = g.logger.Handler().Handle(ctx, r)
+}
+
+func handlerWithSpanContext(handler slog.Handler, o *options) *spanContextLogHandler {
+	h := &spanContextLogHandler{
+		Handler:        handler,
+		level:          o.level,
+		projectID:      o.projectID,
+		spanEvents:     o.spanEvents,
+		sourceLocation: o.sourceLocation,
+	}
+	for _, s := range o.sinks {
+		if s.level != nil {
+			h.sinkLevels = append(h.sinkLevels, s.level)
+		}
+	}
+	if o.redaction != nil {
+		h.redactor = newRedactor(*o.redaction, o.projectID)
+	}
+
+	return h
 }
 
 // spanContextLogHandler is an slog.Handler which adds attributes from the
 // span context.
 type spanContextLogHandler struct {
 	slog.Handler
+	level slog.Leveler
+	// sinkLevels are the levels of the sinks, which may be below level.
+	sinkLevels []slog.Leveler
+	// projectID is the GCP project of the traces, detected when empty.
+	projectID string
+	// redactor hides secrets and PII of the message and the caller's
+	// attributes. Nil when redaction is disabled.
+	redactor *redactor
+	// spanEvents is the level from which the entries are added as events
+	// of the active span. Nil when disabled.
+	spanEvents slog.Leveler
+	// sourceLocation adds the file, line and function of the caller.
+	sourceLocation bool
+	// goas holds the groups and attributes added with WithGroup and
+	// WithAttrs. They are applied on Handle so that the span context
+	// attributes stay at the top level, where Cloud Logging expects them.
+	goas []groupOrAttrs
+}
+
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
+}
+
+// Enabled reports whether the level is at or above the configured minimum,
+// or the level of a sink. The outputs filter the records again by their own
+// level.
+func (t *spanContextLogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if levelEnabled(ctx, t.level, level) {
+		return true
+	}
+
+	for _, l := range t.sinkLevels {
+		if level >= l.Level() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// levelEnabled reports whether level is at or above the minimum of leveler,
+// which a LevelController may set per context. Contexts marked with
+// context_util.SetDebugOn are elevated to debug.
+func levelEnabled(ctx context.Context, leveler slog.Leveler, level slog.Level) bool {
+	minimum := leveler.Level()
+	if l, ok := leveler.(contextLeveler); ok {
+		minimum = l.LevelFor(ctx)
+	}
+
+	if level >= minimum {
+		return true
+	}
+
+	return level >= slog.LevelDebug && context_util.IsDebugOn(ctx)
+}
+
+func (t *spanContextLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return t
+	}
+
+	return t.withGroupOrAttrs(groupOrAttrs{attrs: attrs})
+}
+
+func (t *spanContextLogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return t
+	}
+
+	return t.withGroupOrAttrs(groupOrAttrs{group: name})
+}
+
+func (t *spanContextLogHandler) withGroupOrAttrs(goa groupOrAttrs) *spanContextLogHandler {
+	h := *t
+	h.goas = append(append([]groupOrAttrs{}, t.goas...), goa)
+
+	return &h
+}
+
+// userAttrs returns the attributes of the record nested in the groups
+// opened with WithGroup, preceded by the ones added with WithAttrs.
+func (t *spanContextLogHandler) userAttrs(record slog.Record) []slog.Attr {
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+
+	return nestAttrs(t.goas, attrs)
+}
+
+// nestAttrs nests attrs in the groups of goas, preceded by their attributes.
+func nestAttrs(goas []groupOrAttrs, attrs []slog.Attr) []slog.Attr {
+	for i := len(goas) - 1; i >= 0; i-- {
+		goa := goas[i]
+		if goa.group != "" {
+			attrs = []slog.Attr{{Key: goa.group, Value: slog.GroupValue(attrs...)}}
+			continue
+		}
+
+		attrs = append(append([]slog.Attr{}, goa.attrs...), attrs...)
+	}
+
+	return attrs
 }
 
 // Handle overrides slog.Handler's Handle method. This adds attributes from the
 // span context to the slog.Record.
-func (t *spanContextLogHandler) Handle(ctx context.Context, record slog.Record) error {
+func (t *spanContextLogHandler) Handle(ctx context.Context, r slog.Record) error {
+	msg, attrs := r.Message, t.userAttrs(r)
+	if t.redactor != nil {
+		msg, attrs = t.redactor.String(msg), t.redactor.Attrs(attrs)
+	}
+
+	record := slog.NewRecord(r.Time, r.Level, msg, r.PC)
+	record.AddAttrs(attrs...)
+
+	errReport, reported := ctx.Value(report{}).(*errorReport)
+	if t.spanEvents != nil && !(reported && errReport.recorded) {
+		if reported {
+			// Traces show where the reported failures occurred.
+			spanFromContext(ctx).SetStatus(codes.Error, msg)
+		}
+		if reported || r.Level >= t.spanEvents.Level() {
+			addSpanEvent(ctx, r.Level, msg, attrs)
+		}
+	}
+
 	s := getSpanContext(ctx)
 
 	if s.IsValid() {
 		// Add trace context attributes following Cloud Logging structured log format described
 		// in https://cloud.google.com/logging/docs/structured-logging#special-payload-fields
 		record.AddAttrs(
-			slog.Any("logging.googleapis.com/trace", "projects/proper-base/traces/"+s.TraceID().String()),
+			slog.Any("logging.googleapis.com/trace", "projects/"+t.project()+"/traces/"+s.TraceID().String()),
 		)
 		record.AddAttrs(
-			slog.Any("logging.googleapis.com/spanId", s.SpanID()),
+			slog.String("logging.googleapis.com/spanId", s.SpanID().String()),
 		)
 		record.AddAttrs(
 			slog.Bool("logging.googleapis.com/trace_sampled", s.TraceFlags().IsSampled()),
 		)
 	}
 
+	if t.sourceLocation && r.PC != 0 {
+		record.AddAttrs(sourceLocationAttr(r.PC))
+	}
+
 	record.AddAttrs(
 		slog.String("app", context_util.GetServiceName(ctx)),
 	)
@@ -89,22 +357,29 @@ func (t *spanContextLogHandler) Handle(ctx context.Context, record slog.Record)
 		slog.String("root-task-id", context_util.GetRootTaskID(ctx)),
 	)
 
-	if ctx.Value(report{}) != nil {
-		record.AddAttrs(
-			slog.String("@type", "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent"),
-		)
+	if labels, ok := labelsAttr(ctx); ok {
+		record.AddAttrs(labels)
+	}
 
-		stack, ok := ctx.Value(report{}).([]byte)
-		if ok {
-			record.AddAttrs(
-				slog.String("stack_trace", string(stack)),
-			)
-		}
+	if op, ok := operationAttr(ctx); ok {
+		record.AddAttrs(op)
+	}
+
+	if reported {
+		record.AddAttrs(errReport.attrs(ctx)...)
 	}
 
 	return t.Handler.Handle(ctx, record)
 }
 
+func (t *spanContextLogHandler) project() string {
+	if t.projectID != "" {
+		return t.projectID
+	}
+
+	return detectedProjectID()
+}
+
 func getSpanContext(ctx context.Context) trace.SpanContext {
 	s := trace.SpanContextFromContext(ctx)
 	if !s.IsValid() {
@@ -121,15 +396,23 @@ func getSpanContext(ctx context.Context) trace.SpanContext {
 }
 
 func replacer(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) != 0 {
+		return a
+	}
+
 	// Rename attribute keys to match Cloud Logging structured log format
 	switch a.Key {
 	case slog.LevelKey:
+		// Attributes added by callers may also be named "level".
+		level, ok := a.Value.Any().(slog.Level)
+		if !ok {
+			break
+		}
+
 		a.Key = "severity"
 		// Map slog.Level string values to Cloud Logging LogSeverity
 		// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#LogSeverity
-		if level := a.Value.Any().(slog.Level); level == slog.LevelWarn {
-			a.Value = slog.StringValue("WARNING")
-		}
+		a.Value = slog.StringValue(severity(level))
 	case slog.TimeKey:
 		a.Key = "timestamp"
 	case slog.MessageKey:
@@ -1,11 +1,19 @@
 package logging
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/propertechnologies/monitor/context_util"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestGCPLogging(t *testing.T) {
@@ -21,6 +29,157 @@ func TestGCPLogging(t *testing.T) {
 	assert.NotEmpty(t, n.errorMessage.StackTrace)
 }
 
+func TestThatDebugIsFilteredAtDefaultLevel(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "")
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b))
+
+	Debugf(ctx, "hidden")
+
+	assert.Empty(t, b.String())
+}
+
+func TestThatDebugOnContextElevatesTheLevel(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b, WithLevel(slog.LevelInfo)))
+	ctx = context_util.SetDebugOn(ctx)
+
+	Debugf(ctx, "visible")
+
+	assert.Contains(t, b.String(), `"severity":"DEBUG"`)
+	assert.Contains(t, b.String(), "visible")
+}
+
+func TestThatLevelIsReadFromEnv(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "warning")
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b))
+
+	Infof(ctx, "hidden")
+	assert.Empty(t, b.String())
+
+	Warnf(ctx, "visible")
+	assert.Contains(t, b.String(), `"severity":"WARNING"`)
+}
+
+func TestThatLoggersOnlyWriteToTheirOwnWriter(t *testing.T) {
+	first, second := &syncBuffer{}, &syncBuffer{}
+	firstCtx := SetLogger(context.Background(), NewLoggerWithWriter(first))
+	secondCtx := SetLogger(context.Background(), NewLoggerWithWriter(second))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			Infof(firstCtx, "first")
+		}()
+		go func() {
+			defer wg.Done()
+			Info(secondCtx, "second")
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 50, strings.Count(first.String(), `"message":"first"`))
+	assert.NotContains(t, first.String(), "second")
+	assert.Equal(t, 50, strings.Count(second.String(), `"message":"second"`))
+	assert.NotContains(t, second.String(), `"first"`)
+}
+
+func TestThatSetDefaultIsOptIn(t *testing.T) {
+	previous := slog.Default()
+	defer slog.SetDefault(previous)
+
+	b := &bytes.Buffer{}
+	logger := NewLoggerWithWriter(b)
+	assert.Equal(t, previous, slog.Default())
+
+	logger.SetDefault()
+	slog.Info("through default")
+
+	assert.Contains(t, b.String(), "through default")
+}
+
+func TestThatTraceUsesTheConfiguredProject(t *testing.T) {
+	tid, _ := trace.TraceIDFromHex("0af7651916cd43dd8448eb211c80319c")
+	sid, _ := trace.SpanIDFromHex("b7ad6b7169203331")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: tid,
+		SpanID:  sid,
+	}))
+
+	b := &bytes.Buffer{}
+	ctx = SetLogger(ctx, NewLoggerWithWriter(b, WithProjectID("other-project")))
+
+	Infof(ctx, "traced")
+
+	assert.Contains(t, b.String(), `"logging.googleapis.com/trace":"projects/other-project/traces/0af7651916cd43dd8448eb211c80319c"`)
+}
+
+func TestThatProjectIsDetectedFromEnv(t *testing.T) {
+	t.Setenv("GCP_PROJECT_ID", "")
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "cloud-project")
+
+	assert.Equal(t, "cloud-project", detectProjectID())
+
+	t.Setenv("GCP_PROJECT_ID", "gcp-project")
+
+	assert.Equal(t, "gcp-project", detectProjectID())
+}
+
+func TestThatSourceLocationIsTheCaller(t *testing.T) {
+	b := &bytes.Buffer{}
+	logger := NewLoggerWithWriter(b)
+	ctx := SetLogger(context.Background(), logger)
+
+	_, file, line, _ := runtime.Caller(0)
+	Infof(ctx, "through helper")
+	logger.Infof(ctx, "through wrapper")
+	Info(With(ctx, "k", "v"), "through structured")
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	assert.Len(t, lines, 3)
+
+	for i, l := range lines {
+		var entry struct {
+			Location map[string]string `json:"logging.googleapis.com/sourceLocation"`
+		}
+		assert.NoError(t, json.Unmarshal([]byte(l), &entry))
+		assert.Equal(t, file, entry.Location["file"])
+		assert.Equal(t, strconv.Itoa(line+1+i), entry.Location["line"])
+		assert.Equal(t, "github.com/propertechnologies/monitor/logging.TestThatSourceLocationIsTheCaller", entry.Location["function"])
+	}
+}
+
+func TestThatSourceLocationCanBeDisabled(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b, WithoutSourceLocation()))
+
+	Infof(ctx, "hot path")
+
+	assert.NotContains(t, b.String(), "sourceLocation")
+}
+
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.b.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.b.String()
+}
+
 type errorMessage struct {
 	Severity   string `json:"severity"`
 	Message    string `json:"message"`
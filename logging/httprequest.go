@@ -0,0 +1,179 @@
+package logging
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// HTTPRequest is rendered as the httpRequest special field, which Cloud
+	// Logging displays as a request entry.
+	// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#HttpRequest
+	HTTPRequest struct {
+		Method       string
+		URL          string
+		Status       int
+		Latency      time.Duration
+		RequestSize  int64
+		ResponseSize int64
+		UserAgent    string
+		RemoteIP     string
+		Referer      string
+		Protocol     string
+	}
+
+	statusRecorder struct {
+		http.ResponseWriter
+		status      int
+		size        int64
+		wroteHeader bool
+	}
+)
+
+// LogValue implements slog.LogValuer, omitting the fields that are not set.
+func (h HTTPRequest) LogValue() slog.Value {
+	attrs := []slog.Attr{slog.String("requestMethod", h.Method), slog.String("requestUrl", h.URL)}
+
+	if h.Status != 0 {
+		attrs = append(attrs, slog.Int("status", h.Status))
+	}
+	if h.Latency != 0 {
+		attrs = append(attrs, slog.String("latency", fmt.Sprintf("%.9fs", h.Latency.Seconds())))
+	}
+	// Sizes are int64 values, which the LogEntry JSON encodes as strings.
+	if h.RequestSize > 0 {
+		attrs = append(attrs, slog.String("requestSize", strconv.FormatInt(h.RequestSize, 10)))
+	}
+	if h.ResponseSize > 0 {
+		attrs = append(attrs, slog.String("responseSize", strconv.FormatInt(h.ResponseSize, 10)))
+	}
+
+	for _, a := range []slog.Attr{
+		slog.String("userAgent", h.UserAgent),
+		slog.String("remoteIp", h.RemoteIP),
+		slog.String("referer", h.Referer),
+		slog.String("protocol", h.Protocol),
+	} {
+		if a.Value.String() != "" {
+			attrs = append(attrs, a)
+		}
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+// LogHTTPRequest logs req as a request entry. Server errors are logged as
+// errors and client errors as warnings.
+func LogHTTPRequest(ctx context.Context, req HTTPRequest, args ...interface{}) {
+	level := slog.LevelInfo
+	switch {
+	case req.Status >= http.StatusInternalServerError:
+		level = slog.LevelError
+	case req.Status >= http.StatusBadRequest:
+		level = slog.LevelWarn
+	}
+
+	msg := fmt.Sprintf("%s %s %d", req.Method, req.URL, req.Status)
+
+	logAttrs(ctx, level, msg, append([]interface{}{slog.Any("httpRequest", req)}, args...)...)
+}
+
+// AccessLogMiddleware logs every request served by next with LogHTTPRequest.
+// Requests see the values of ctx, such as its logger, unless they carry
+// their own.
+func AccessLogMiddleware(ctx context.Context, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rec, r)
+
+		LogHTTPRequest(withValuesFrom(r.Context(), ctx), HTTPRequest{
+			Method:       r.Method,
+			URL:          requestURL(r),
+			Status:       rec.status,
+			Latency:      time.Since(start),
+			RequestSize:  r.ContentLength,
+			ResponseSize: rec.size,
+			UserAgent:    r.UserAgent(),
+			RemoteIP:     remoteIP(r),
+			Referer:      r.Referer(),
+			Protocol:     r.Proto,
+		})
+	})
+}
+
+// WriteHeader records only the first status, the one actually sent.
+func (s *statusRecorder) WriteHeader(status int) {
+	if !s.wroteHeader {
+		s.status = status
+		s.wroteHeader = true
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	s.wroteHeader = true
+	n, err := s.ResponseWriter.Write(b)
+	s.size += int64(n)
+
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the original writer.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// Flush keeps streaming handlers working behind the middleware.
+func (s *statusRecorder) Flush() {
+	s.wroteHeader = true
+	_ = http.NewResponseController(s.ResponseWriter).Flush()
+}
+
+// Hijack hands the connection over, as websocket upgrades expect.
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(s.ResponseWriter).Hijack()
+	if err == nil {
+		s.wroteHeader = true
+	}
+
+	return conn, rw, err
+}
+
+func requestURL(r *http.Request) string {
+	if r.URL.IsAbs() {
+		return r.URL.String()
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+func remoteIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		ip, _, _ := strings.Cut(forwarded, ",")
+		return strings.TrimSpace(ip)
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThatAccessLogEmitsTheHTTPRequestField(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b))
+
+	handler := AccessLogMiddleware(ctx, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("missing"))
+	}))
+
+	req := httptest.NewRequest("POST", "http://bank.example.com/accounts?id=1", strings.NewReader("{}"))
+	req.Header.Set("User-Agent", "bot/1.0")
+	req.Header.Set("X-Forwarded-For", "10.0.0.1, 10.0.0.2")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry struct {
+		Severity    string `json:"severity"`
+		HTTPRequest struct {
+			RequestMethod string `json:"requestMethod"`
+			RequestURL    string `json:"requestUrl"`
+			Status        int    `json:"status"`
+			Latency       string `json:"latency"`
+			RequestSize   string `json:"requestSize"`
+			ResponseSize  string `json:"responseSize"`
+			UserAgent     string `json:"userAgent"`
+			RemoteIP      string `json:"remoteIp"`
+			Protocol      string `json:"protocol"`
+		} `json:"httpRequest"`
+	}
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &entry))
+	assert.Equal(t, "WARNING", entry.Severity)
+	assert.Equal(t, "POST", entry.HTTPRequest.RequestMethod)
+	assert.Equal(t, "http://bank.example.com/accounts?id=1", entry.HTTPRequest.RequestURL)
+	assert.Equal(t, 404, entry.HTTPRequest.Status)
+	assert.True(t, strings.HasSuffix(entry.HTTPRequest.Latency, "s"))
+	assert.Equal(t, "2", entry.HTTPRequest.RequestSize)
+	assert.Equal(t, "7", entry.HTTPRequest.ResponseSize)
+	assert.Equal(t, "bot/1.0", entry.HTTPRequest.UserAgent)
+	assert.Equal(t, "10.0.0.1", entry.HTTPRequest.RemoteIP)
+	assert.Equal(t, "HTTP/1.1", entry.HTTPRequest.Protocol)
+}
+
+func TestThatAccessLogKeepsTheFirstStatusAndFlushes(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b))
+
+	handler := AccessLogMiddleware(ctx, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("streaming"))
+		w.(http.Flusher).Flush()
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest("GET", "http://bank.example.com/events", nil))
+
+	assert.True(t, res.Flushed)
+	assert.Contains(t, b.String(), `"status":200`)
+	assert.Contains(t, b.String(), `"severity":"INFO"`)
+}
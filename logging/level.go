@@ -0,0 +1,281 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/propertechnologies/monitor/context_util"
+)
+
+// Scopes of the levels set on a LevelController, matched against the
+// context_util values of the logging context.
+const (
+	ScopeService = "service"
+	ScopeBot     = "bot"
+	ScopeFlowID  = "flow-id"
+)
+
+type (
+	// LevelController holds a minimum level which can be changed at runtime,
+	// globally or for a service, bot or flow. Give it to WithLevel.
+	LevelController struct {
+		mu     sync.RWMutex
+		level  slog.Level
+		scoped map[levelScope]slog.Level
+		// toggled is the level to restore when debug is toggled off.
+		toggled *slog.Level
+	}
+
+	levelScope struct {
+		scope string
+		name  string
+	}
+
+	// contextLeveler is a leveler whose level depends on the context.
+	contextLeveler interface {
+		LevelFor(ctx context.Context) slog.Level
+	}
+
+	levelState struct {
+		Level  string            `json:"level"`
+		Scoped map[string]string `json:"scoped,omitempty"`
+	}
+)
+
+// NewLevelController returns a controller starting at LOG_LEVEL.
+func NewLevelController() *LevelController {
+	return &LevelController{level: levelFromEnv(), scoped: map[levelScope]slog.Level{}}
+}
+
+// Level returns the global level.
+func (c *LevelController) Level() slog.Level {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.level
+}
+
+func (c *LevelController) SetLevel(level slog.Level) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.level, c.toggled = level, nil
+}
+
+// SetScopedLevel sets the level of the entries logged for the given service,
+// bot or flow-id, whatever the global level.
+func (c *LevelController) SetScopedLevel(scope, name string, level slog.Level) error {
+	if err := validScope(scope, name); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.scoped[levelScope{scope: scope, name: name}] = level
+
+	return nil
+}
+
+// ResetScopedLevel makes the scope follow the global level again.
+func (c *LevelController) ResetScopedLevel(scope, name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.scoped, levelScope{scope: scope, name: name})
+}
+
+// LevelFor returns the level of the most specific scope of ctx: its flow,
+// then its bot, then its service, and the global level otherwise.
+func (c *LevelController) LevelFor(ctx context.Context) slog.Level {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if len(c.scoped) != 0 {
+		for _, s := range []levelScope{
+			{scope: ScopeFlowID, name: context_util.GetFlowID(ctx)},
+			{scope: ScopeBot, name: context_util.GetBotName(ctx)},
+			{scope: ScopeService, name: context_util.GetServiceName(ctx)},
+		} {
+			if level, ok := c.scoped[s]; ok {
+				return level
+			}
+		}
+	}
+
+	return c.level
+}
+
+// ToggleDebug switches the global level to debug, or back to the level it
+// had before.
+func (c *LevelController) ToggleDebug() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.toggled != nil {
+		c.level, c.toggled = *c.toggled, nil
+		return
+	}
+
+	previous := c.level
+	c.level, c.toggled = slog.LevelDebug, &previous
+}
+
+// ServeHTTP is an admin endpoint to read and change the levels. GET returns
+// them, POST sets the level given as ?level=, for the ?scope= and ?name=
+// when given. An empty level resets the scope. It doesn't authenticate the
+// callers, so it must only be served on an internal port.
+func (c *LevelController) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost, http.MethodPut:
+		if err := c.update(r.FormValue("scope"), r.FormValue("name"), r.FormValue("level")); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST, PUT")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(c.state())
+}
+
+// WatchEnvFile polls the file at path every interval until ctx is done, and
+// applies its levels when it changes. The file holds LOG_LEVEL=<level> for
+// the global level and LOG_LEVEL.<scope>.<name>=<level> lines for the
+// scopes, which replace the ones set before.
+func (c *LevelController) WatchEnvFile(ctx context.Context, path string, interval time.Duration) {
+	var modified time.Time
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if info, err := os.Stat(path); err == nil && !info.ModTime().Equal(modified) {
+			modified = info.ModTime()
+			if err := c.loadEnvFile(path); err != nil {
+				fmt.Fprintf(os.Stderr, "logging: reading levels from %s: %v\n", path, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *LevelController) loadEnvFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var (
+		global *slog.Level
+		scoped = map[levelScope]slog.Level{}
+		s      = bufio.NewScanner(bytes.NewReader(b))
+	)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key = strings.TrimPrefix(strings.TrimSpace(key), "export ")
+		if key != levelEnv && !strings.HasPrefix(key, levelEnv+".") {
+			continue
+		}
+
+		level, err := ParseLevel(strings.Trim(strings.TrimSpace(value), `"'`))
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+
+		if key == levelEnv {
+			global = &level
+			continue
+		}
+
+		scope, name, _ := strings.Cut(strings.TrimPrefix(key, levelEnv+"."), ".")
+		if err := validScope(scope, name); err != nil {
+			return err
+		}
+		scoped[levelScope{scope: scope, name: name}] = level
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if global != nil {
+		c.level, c.toggled = *global, nil
+	}
+	c.scoped = scoped
+
+	return nil
+}
+
+func (c *LevelController) update(scope, name, value string) error {
+	if scope == "" {
+		level, err := ParseLevel(value)
+		if err != nil {
+			return err
+		}
+
+		c.SetLevel(level)
+
+		return nil
+	}
+
+	if value == "" {
+		c.ResetScopedLevel(scope, name)
+		return nil
+	}
+
+	level, err := ParseLevel(value)
+	if err != nil {
+		return err
+	}
+
+	return c.SetScopedLevel(scope, name, level)
+}
+
+func (c *LevelController) state() levelState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	state := levelState{Level: c.level.String(), Scoped: map[string]string{}}
+	for s, level := range c.scoped {
+		state.Scoped[s.scope+"."+s.name] = level.String()
+	}
+
+	return state
+}
+
+func validScope(scope, name string) error {
+	switch scope {
+	case ScopeService, ScopeBot, ScopeFlowID:
+	default:
+		return fmt.Errorf("logging: unknown level scope %q", scope)
+	}
+
+	if name == "" {
+		return fmt.Errorf("logging: missing %s name", scope)
+	}
+
+	return nil
+}
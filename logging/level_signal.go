@@ -0,0 +1,26 @@
+//go:build !windows
+
+package logging
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ToggleDebugOnSignal toggles debug on every SIGUSR1 until stop is called.
+func (c *LevelController) ToggleDebugOnSignal() (stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+
+	go func() {
+		for range signals {
+			c.ToggleDebug()
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(signals)
+	}
+}
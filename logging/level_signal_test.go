@@ -0,0 +1,24 @@
+//go:build !windows
+
+package logging
+
+import (
+	"log/slog"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThatSIGUSR1TogglesDebug(t *testing.T) {
+	c := NewLevelController()
+	c.SetLevel(slog.LevelInfo)
+
+	stop := c.ToggleDebugOnSignal()
+	defer stop()
+
+	assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	assert.Eventually(t, func() bool { return c.Level() == slog.LevelDebug }, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,6 @@
+package logging
+
+// ToggleDebugOnSignal does nothing, as there is no SIGUSR1 on Windows.
+func (c *LevelController) ToggleDebugOnSignal() (stop func()) {
+	return func() {}
+}
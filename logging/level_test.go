@@ -0,0 +1,81 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/propertechnologies/monitor/context_util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThatScopedLevelsApplyToTheirContexts(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "")
+
+	c := NewLevelController()
+	assert.NoError(t, c.SetScopedLevel(ScopeService, "payroll", slog.LevelDebug))
+
+	b := &bytes.Buffer{}
+	logger := NewLoggerWithWriter(b, WithLevel(c))
+	payroll := SetLogger(context_util.SetServiceName(context.Background(), "payroll"), logger)
+	other := SetLogger(context.Background(), logger)
+
+	Debugf(payroll, "payroll debug")
+	Debugf(other, "other debug")
+	assert.Contains(t, b.String(), "payroll debug")
+	assert.NotContains(t, b.String(), "other debug")
+
+	c.ResetScopedLevel(ScopeService, "payroll")
+	c.SetLevel(slog.LevelWarn)
+	Infof(payroll, "payroll info")
+	assert.NotContains(t, b.String(), "payroll info")
+
+	assert.Error(t, c.SetScopedLevel("team", "bots", slog.LevelDebug))
+}
+
+func TestThatDebugIsToggled(t *testing.T) {
+	c := NewLevelController()
+	c.SetLevel(slog.LevelWarn)
+
+	c.ToggleDebug()
+	assert.Equal(t, slog.LevelDebug, c.Level())
+
+	c.ToggleDebug()
+	assert.Equal(t, slog.LevelWarn, c.Level())
+}
+
+func TestThatLevelsAreChangedThroughHTTP(t *testing.T) {
+	c := NewLevelController()
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/log-level?scope=bot&name=santander&level=debug", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"level":"INFO","scoped":{"bot.santander":"DEBUG"}}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/log-level?level=loud", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/log-level?scope=bot&name=santander", nil))
+	assert.JSONEq(t, `{"level":"INFO"}`, rec.Body.String())
+}
+
+func TestThatLevelsAreReadFromTheEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "levels.env")
+	assert.NoError(t, os.WriteFile(path, []byte("# levels\nLOG_LEVEL=warning\nLOG_LEVEL.flow-id.f-1=debug\n"), 0o644))
+
+	c := NewLevelController()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.WatchEnvFile(ctx, path, 10*time.Millisecond)
+
+	assert.Eventually(t, func() bool { return c.Level() == slog.LevelWarn }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, slog.LevelDebug, c.LevelFor(context.WithValue(context.Background(), "FlowID", "f-1")))
+}
@@ -0,0 +1,358 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// defaultMaxEntrySize leaves room below Cloud Logging's 256KB limit for
+	// the metadata added by the logging agent.
+	defaultMaxEntrySize = 250 << 10
+	truncatedMarker     = "...[truncated %d bytes]"
+	// markerRoom is the most bytes a truncation marker takes.
+	markerRoom = 48
+	// chunkRoom is kept in each chunk for its insertId and chunk fields.
+	chunkRoom = 160
+	// maxTruncations bounds the fields shortened before falling back to the
+	// bare message.
+	maxTruncations = 16
+	// jsonExpansion is the most a string grows when escaped for JSON, used to
+	// skip measuring entries that can't be over the limit.
+	jsonExpansion = 6
+	// protectedPrefix marks the Cloud Logging fields that are never shortened.
+	protectedPrefix = "logging.googleapis.com/"
+)
+
+type (
+	// EntryLimitOptions configures how entries over the Cloud Logging size
+	// limit are handled. By default their largest fields are truncated.
+	EntryLimitOptions struct {
+		// MaxBytes is the size of the encoded entry allowed. Defaults to
+		// 250KB.
+		MaxBytes int
+		// Split writes large messages as several entries, sharing an
+		// insertId prefix and numbered with a chunk field, instead of
+		// truncating them.
+		Split bool
+	}
+
+	entryLimitHandler struct {
+		slog.Handler
+		opts EntryLimitOptions
+	}
+
+	byteCounter int
+)
+
+// reportKeys are the Error Reporting fields, only written with the first
+// chunk of a split entry so that it's reported once.
+var reportKeys = map[string]bool{
+	"@type":          true,
+	"stack_trace":    true,
+	"serviceContext": true,
+	"context":        true,
+}
+
+// WithEntryLimit configures the handling of entries over the size limit.
+func WithEntryLimit(opts EntryLimitOptions) Option {
+	return func(o *options) {
+		o.entryLimit = &opts
+	}
+}
+
+// WithoutEntryLimit writes entries whatever their size.
+func WithoutEntryLimit() Option {
+	return func(o *options) {
+		o.entryLimit = nil
+	}
+}
+
+func newEntryLimitHandler(h slog.Handler, opts EntryLimitOptions) *entryLimitHandler {
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = defaultMaxEntrySize
+	}
+
+	return &entryLimitHandler{Handler: h, opts: opts}
+}
+
+func (h *entryLimitHandler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := recordAttrs(r)
+	if n, ok := attrsLen(attrs); (ok && jsonExpansion*(len(r.Message)+n) < h.opts.MaxBytes) || encodedSize(r) <= h.opts.MaxBytes {
+		return h.Handler.Handle(ctx, r)
+	}
+
+	if h.opts.Split {
+		return h.split(ctx, r, attrs)
+	}
+
+	msg, attrs := h.truncate(r, r.Message, attrs, h.opts.MaxBytes)
+
+	return h.Handler.Handle(ctx, newRecordFrom(r, msg, attrs))
+}
+
+func (h *entryLimitHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &entryLimitHandler{Handler: h.Handler.WithAttrs(attrs), opts: h.opts}
+}
+
+func (h *entryLimitHandler) WithGroup(name string) slog.Handler {
+	return &entryLimitHandler{Handler: h.Handler.WithGroup(name), opts: h.opts}
+}
+
+// split writes the message in chunks of the room left by the attributes,
+// which are shortened first if they take more than half of the limit.
+func (h *entryLimitHandler) split(ctx context.Context, r slog.Record, attrs []slog.Attr) error {
+	_, attrs = h.truncate(r, "", attrs, h.opts.MaxBytes/2)
+
+	room := h.opts.MaxBytes - encodedSize(newRecordFrom(r, "", attrs)) - chunkRoom
+	// Leave room for the characters escaped in JSON.
+	if escaped, err := json.Marshal(r.Message); err == nil && len(escaped) > len(r.Message) {
+		room = room * len(r.Message) / len(escaped)
+	}
+
+	chunks := splitString(r.Message, room)
+	id := insertIDPrefix()
+
+	// The next chunks are plain continuations of the first.
+	var continued []slog.Attr
+	for _, a := range attrs {
+		if !reportKeys[a.Key] {
+			continued = append(continued, a)
+		}
+	}
+
+	for i, chunk := range chunks {
+		record := newRecordFrom(r, chunk, attrs)
+		if i > 0 {
+			record = newRecordFrom(r, chunk, continued)
+		}
+		record.AddAttrs(
+			slog.String(protectedPrefix+"insertId", fmt.Sprintf("%s-%04d", id, i)),
+			slog.Group("chunk", slog.Int("index", i), slog.Int("count", len(chunks))),
+		)
+
+		if err := h.Handler.Handle(ctx, record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// truncate shortens the largest of the message and the attributes until the
+// entry fits in max bytes.
+func (h *entryLimitHandler) truncate(r slog.Record, msg string, attrs []slog.Attr, max int) (string, []slog.Attr) {
+	for i := 0; i < maxTruncations; i++ {
+		size := encodedSize(newRecordFrom(r, msg, attrs))
+		if size <= max {
+			return msg, attrs
+		}
+
+		// Each byte cut saves at least one encoded byte.
+		excess := size - max + markerRoom
+
+		path, n := largestAttr(attrs)
+		if len(msg) >= n {
+			msg = truncateString(msg, len(msg)-excess)
+			continue
+		}
+
+		attrs = replaceAttr(attrs, path, func(a slog.Attr) slog.Attr {
+			s := attrString(a.Value)
+			return slog.String(a.Key, truncateString(s, len(s)-excess))
+		})
+	}
+
+	// Keep what Cloud Logging needs to correlate the entry.
+	var kept []slog.Attr
+	for _, a := range attrs {
+		if strings.HasPrefix(a.Key, protectedPrefix) {
+			kept = append(kept, a)
+		}
+	}
+
+	return truncateString(msg, max/2), kept
+}
+
+func recordAttrs(r slog.Record) []slog.Attr {
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, slog.Attr{Key: a.Key, Value: a.Value.Resolve()})
+		return true
+	})
+
+	return attrs
+}
+
+func newRecordFrom(r slog.Record, msg string, attrs []slog.Attr) slog.Record {
+	record := slog.NewRecord(r.Time, r.Level, msg, r.PC)
+	record.AddAttrs(attrs...)
+
+	return record
+}
+
+// encodedSize returns the size of the record as written by the JSON handler.
+func encodedSize(r slog.Record) int {
+	var c byteCounter
+	_ = slog.NewJSONHandler(&c, &slog.HandlerOptions{ReplaceAttr: replacer}).Handle(context.Background(), r)
+
+	return int(c)
+}
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
+
+// attrsLen estimates the raw size of the attributes without encoding them.
+// It reports false when a value's size can't be known cheaply.
+func attrsLen(attrs []slog.Attr) (int, bool) {
+	n := 0
+	for _, a := range attrs {
+		n += len(a.Key)
+
+		switch v := a.Value; v.Kind() {
+		case slog.KindGroup:
+			m, ok := attrsLen(v.Group())
+			if !ok {
+				return 0, false
+			}
+			n += m
+		case slog.KindString:
+			n += len(v.String())
+		case slog.KindAny:
+			switch x := v.Any().(type) {
+			case error:
+				n += len(x.Error())
+			case []byte:
+				// Encoded in base64.
+				n += 2 * len(x)
+			case []string:
+				for _, e := range x {
+					n += len(e) + 3
+				}
+			case fmt.Stringer:
+				n += len(x.String())
+			default:
+				return 0, false
+			}
+		default:
+			// Numbers, booleans, times and durations.
+			n += 40
+		}
+	}
+
+	return n, true
+}
+
+// largestAttr returns the path of the longest attribute that may be
+// truncated, and its length.
+func largestAttr(attrs []slog.Attr) ([]int, int) {
+	var (
+		path []int
+		max  = -1
+	)
+	for i, a := range attrs {
+		if strings.HasPrefix(a.Key, protectedPrefix) {
+			continue
+		}
+
+		if a.Value.Kind() == slog.KindGroup {
+			if p, n := largestAttr(a.Value.Group()); n > max {
+				path, max = append([]int{i}, p...), n
+			}
+			continue
+		}
+
+		if n := len(attrString(a.Value)); n > max {
+			path, max = []int{i}, n
+		}
+	}
+
+	return path, max
+}
+
+// replaceAttr returns a copy of attrs with the attribute at path replaced.
+func replaceAttr(attrs []slog.Attr, path []int, replace func(slog.Attr) slog.Attr) []slog.Attr {
+	replaced := append([]slog.Attr{}, attrs...)
+
+	a := replaced[path[0]]
+	if len(path) == 1 {
+		replaced[path[0]] = replace(a)
+		return replaced
+	}
+
+	replaced[path[0]] = slog.Attr{Key: a.Key, Value: slog.GroupValue(replaceAttr(a.Value.Group(), path[1:], replace)...)}
+
+	return replaced
+}
+
+// attrString returns the value as it would be written, for values whose
+// size matters.
+func attrString(v slog.Value) string {
+	switch v.Kind() {
+	case slog.KindString:
+		return v.String()
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return err.Error()
+		}
+
+		if b, err := json.Marshal(v.Any()); err == nil {
+			return string(b)
+		}
+	}
+
+	return v.String()
+}
+
+// truncateString cuts s to n bytes, on a rune boundary, and appends a marker
+// with the number of bytes removed.
+func truncateString(s string, n int) string {
+	if n >= len(s) {
+		return s
+	}
+
+	if n < 0 {
+		n = 0
+	}
+
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n] + fmt.Sprintf(truncatedMarker, len(s)-n)
+}
+
+// splitString cuts s in chunks of at most size bytes, on rune boundaries.
+func splitString(s string, size int) []string {
+	if size < utf8.UTFMax {
+		size = utf8.UTFMax
+	}
+
+	var chunks []string
+	for len(s) > size {
+		n := size
+		for n > 0 && !utf8.RuneStart(s[n]) {
+			n--
+		}
+
+		chunks = append(chunks, s[:n])
+		s = s[n:]
+	}
+
+	return append(chunks, s)
+}
+
+func insertIDPrefix() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
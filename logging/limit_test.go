@@ -0,0 +1,153 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// lastRecordHandler keeps the last record it handled.
+type lastRecordHandler struct {
+	slog.Handler
+	record *slog.Record
+}
+
+func (h *lastRecordHandler) Handle(_ context.Context, r slog.Record) error {
+	*h.record = r
+	return nil
+}
+
+func decodeEntries(t *testing.T, b *bytes.Buffer) []map[string]interface{} {
+	var entries []map[string]interface{}
+
+	s := bufio.NewScanner(b)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		var e map[string]interface{}
+		assert.NoError(t, json.Unmarshal(s.Bytes(), &e))
+		assert.LessOrEqual(t, len(s.Bytes()), 4096)
+		entries = append(entries, e)
+	}
+
+	return entries
+}
+
+func TestThatLargeMessagesAreTruncated(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b, WithEntryLimit(EntryLimitOptions{MaxBytes: 4096})))
+
+	Infof(ctx, "response: %s", strings.Repeat("é", 10000))
+
+	entries := decodeEntries(t, b)
+	assert.Len(t, entries, 1)
+	assert.True(t, strings.HasPrefix(entries[0]["message"].(string), "response: éé"))
+	assert.Contains(t, entries[0]["message"], "...[truncated ")
+}
+
+func TestThatLargeAttributesAreTruncatedFirst(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b, WithEntryLimit(EntryLimitOptions{MaxBytes: 4096})))
+
+	Info(ctx, "fetched accounts", "body", strings.Repeat("x", 20000), "account_id", "acc-1")
+
+	entries := decodeEntries(t, b)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "fetched accounts", entries[0]["message"])
+	assert.Equal(t, "acc-1", entries[0]["account_id"])
+	assert.Contains(t, entries[0]["body"], "...[truncated ")
+}
+
+func TestThatLargeMessagesAreSplitInChunks(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b, WithEntryLimit(EntryLimitOptions{MaxBytes: 4096, Split: true})))
+
+	body := strings.Repeat("0123456789\n", 1000)
+	Infof(ctx, "%s", body)
+
+	entries := decodeEntries(t, b)
+	assert.Greater(t, len(entries), 2)
+
+	var (
+		message strings.Builder
+		prefix  = strings.Split(entries[0]["logging.googleapis.com/insertId"].(string), "-")[0]
+	)
+	for i, e := range entries {
+		message.WriteString(e["message"].(string))
+		assert.True(t, strings.HasPrefix(e["logging.googleapis.com/insertId"].(string), prefix+"-"))
+		chunk := e["chunk"].(map[string]interface{})
+		assert.Equal(t, float64(i), chunk["index"])
+		assert.Equal(t, float64(len(entries)), chunk["count"])
+	}
+	assert.Equal(t, body, message.String())
+}
+
+func TestThatOnlyTheFirstChunkIsReported(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b, WithEntryLimit(EntryLimitOptions{MaxBytes: 4096, Split: true})))
+
+	Reportf(ctx, "%s", strings.Repeat("0123456789\n", 1000))
+
+	entries := decodeEntries(t, b)
+	assert.Greater(t, len(entries), 2)
+	assert.Equal(t, ReportedErrorEventType, entries[0]["@type"])
+	assert.Contains(t, entries[0], "stack_trace")
+	assert.Contains(t, entries[0], "context")
+
+	for _, e := range entries[1:] {
+		assert.NotContains(t, e, "@type")
+		assert.NotContains(t, e, "stack_trace")
+		assert.NotContains(t, e, "context")
+		assert.Contains(t, e, "chunk")
+	}
+}
+
+func TestThatEntriesWithValuesOfUnknownSizeAreMeasured(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b, WithEntryLimit(EntryLimitOptions{MaxBytes: 2048})))
+
+	Info(ctx, "payload", "body", map[string]string{"data": strings.Repeat("x", 4096)}, "small", map[string]int{"n": 1})
+
+	entries := decodeEntries(t, b)
+	assert.Len(t, entries, 1)
+	assert.LessOrEqual(t, len(b.Bytes()), 2048)
+	assert.Equal(t, map[string]interface{}{"n": float64(1)}, entries[0]["small"])
+}
+
+func TestThatEntriesAreNotLimitedWhenDisabled(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b, WithoutEntryLimit()))
+
+	Infof(ctx, "%s", strings.Repeat("x", 300<<10))
+
+	assert.Greater(t, b.Len(), 300<<10)
+}
+
+func TestThatTracedAndReportedEntriesAreEstimatedWithoutEncoding(t *testing.T) {
+	var r slog.Record
+	output := &lastRecordHandler{Handler: slog.NewJSONHandler(&bytes.Buffer{}, nil), record: &r}
+	logger := &GCPLoggerWrapper{logger: slog.New(handlerWithSpanContext(output, newOptions([]Option{WithProjectID("test")}))), callers: true}
+
+	tid, _ := trace.TraceIDFromHex("0af7651916cd43dd8448eb211c80319c")
+	sid, _ := trace.SpanIDFromHex("b7ad6b7169203331")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: tid,
+		SpanID:  sid,
+	}))
+	ctx = SetLogger(ctx, logger)
+
+	Info(ctx, "synced")
+	_, ok := attrsLen(recordAttrs(r))
+	assert.True(t, ok)
+
+	ReportError(ctx, errors.New("login failed"))
+	_, ok = attrsLen(recordAttrs(r))
+	assert.True(t, ok)
+}
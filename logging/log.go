@@ -3,19 +3,21 @@ package logging
 import (
 	"context"
 	"fmt"
-	"runtime/debug"
 
 	"github.com/propertechnologies/monitor/context_util"
 )
 
 type (
 	Logger interface {
+		Debugf(ctx context.Context, format string, args ...interface{})
 		Infof(ctx context.Context, format string, args ...interface{})
 		Errorf(ctx context.Context, format string, args ...interface{})
 		Warnf(ctx context.Context, format string, args ...interface{})
 	}
 
-	DefaultLogger struct{}
+	DefaultLogger struct {
+		args []interface{}
+	}
 
 	report struct{}
 )
@@ -24,8 +26,15 @@ func NewDefaultLogger() *DefaultLogger {
 	return &DefaultLogger{}
 }
 
+// Debugf only prints when debug is on for the context.
+func (l *DefaultLogger) Debugf(ctx context.Context, format string, args ...interface{}) {
+	if context_util.IsDebugOn(ctx) {
+		l.Infof(ctx, format, args...)
+	}
+}
+
 func (l *DefaultLogger) Infof(ctx context.Context, format string, args ...interface{}) {
-	message := fmt.Sprintf(format, args...)
+	message := formatAttrs(fmt.Sprintf(format, args...), l.args)
 	fmt.Println(message)
 }
 
@@ -41,6 +50,11 @@ func (l *DefaultLogger) Warnf(ctx context.Context, format string, args ...interf
 	l.Infof(ctx, format, args...)
 }
 
+func Debugf(ctx context.Context, format string, args ...interface{}) {
+	log := GetLoggerOrDefault(ctx, &DefaultLogger{})
+	log.Debugf(ctx, format, args...)
+}
+
 func Infof(ctx context.Context, format string, args ...interface{}) {
 	log := GetLoggerOrDefault(ctx, &DefaultLogger{})
 	log.Infof(ctx, format, args...)
@@ -57,8 +71,12 @@ func Warnf(ctx context.Context, format string, args ...interface{}) {
 }
 
 func Reportf(ctx context.Context, format string, args ...interface{}) {
+	reportf(ctx, newErrorReport(callerFrames()), format, args...)
+}
+
+func reportf(ctx context.Context, r *errorReport, format string, args ...interface{}) {
 	log := GetLoggerOrDefault(ctx, &DefaultLogger{})
-	ctx = context.WithValue(ctx, report{}, debug.Stack())
+	ctx = context.WithValue(ctx, report{}, r)
 
 	log.Errorf(ctx, format, args...)
 }
@@ -0,0 +1,196 @@
+// Package logtest captures the entries written by a logging logger so tests
+// can assert on them.
+//
+//	rec := logtest.New()
+//	ctx := logging.SetLogger(context.Background(), rec)
+//	...
+//	rec.AssertLogged(t, slog.LevelError, "failed to login")
+package logtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/propertechnologies/monitor/logging"
+)
+
+type (
+	// Record is a captured log entry.
+	Record struct {
+		Time    time.Time
+		Level   slog.Level
+		Message string
+		// Attrs holds every other field of the entry, as decoded from JSON.
+		Attrs   map[string]interface{}
+		TraceID string
+		SpanID  string
+		// Reported is set for entries sent to Error Reporting.
+		Reported bool
+	}
+
+	// Recorder is a logging.Logger keeping every entry in memory. It's safe
+	// for concurrent use.
+	Recorder struct {
+		*logging.GCPLoggerWrapper
+		sink *sink
+	}
+
+	sink struct {
+		mu      sync.Mutex
+		records []Record
+	}
+)
+
+// New returns a Recorder logging every level in JSON, as written by the
+// code under test: without redaction, entry limit or source location. opts
+// are applied after these defaults, so WithRedaction, WithEntryLimit or
+// WithSourceLocation turn them back on.
+func New(opts ...logging.Option) *Recorder {
+	s := &sink{}
+
+	defaults := []logging.Option{
+		logging.WithLevel(slog.LevelDebug),
+		logging.WithFormat(logging.FormatJSON),
+		logging.WithProjectID("test"),
+		logging.WithoutRedaction(),
+		logging.WithoutEntryLimit(),
+		logging.WithoutSourceLocation(),
+	}
+
+	return &Recorder{
+		GCPLoggerWrapper: logging.NewLoggerWithWriter(s, append(defaults, opts...)...),
+		sink:             s,
+	}
+}
+
+// Records returns a copy of the captured entries, oldest first.
+func (r *Recorder) Records() []Record {
+	r.sink.mu.Lock()
+	defer r.sink.mu.Unlock()
+
+	return append([]Record{}, r.sink.records...)
+}
+
+// Filter returns the entries for which match returns true.
+func (r *Recorder) Filter(match func(Record) bool) []Record {
+	var records []Record
+	for _, rec := range r.Records() {
+		if match(rec) {
+			records = append(records, rec)
+		}
+	}
+
+	return records
+}
+
+// ByLevel returns the entries logged at level.
+func (r *Recorder) ByLevel(level slog.Level) []Record {
+	return r.Filter(func(rec Record) bool { return rec.Level == level })
+}
+
+// Contains reports whether an entry's message contains substr.
+func (r *Recorder) Contains(substr string) bool {
+	return len(r.Filter(func(rec Record) bool { return strings.Contains(rec.Message, substr) })) != 0
+}
+
+// Reported returns the entries sent to Error Reporting.
+func (r *Recorder) Reported() []Record {
+	return r.Filter(func(rec Record) bool { return rec.Reported })
+}
+
+// Reset drops the captured entries.
+func (r *Recorder) Reset() {
+	r.sink.mu.Lock()
+	defer r.sink.mu.Unlock()
+
+	r.sink.records = nil
+}
+
+// AssertLogged fails t unless an entry at level contains substr, and returns
+// the first one.
+func (r *Recorder) AssertLogged(t testing.TB, level slog.Level, substr string) Record {
+	t.Helper()
+
+	records := r.Filter(func(rec Record) bool {
+		return rec.Level == level && strings.Contains(rec.Message, substr)
+	})
+	if len(records) == 0 {
+		t.Errorf("no %s entry containing %q, got:\n%s", level, substr, r.dump())
+		return Record{}
+	}
+
+	return records[0]
+}
+
+// AssertNotLogged fails t if any entry contains substr.
+func (r *Recorder) AssertNotLogged(t testing.TB, substr string) {
+	t.Helper()
+
+	if r.Contains(substr) {
+		t.Errorf("unexpected entry containing %q, got:\n%s", substr, r.dump())
+	}
+}
+
+// Attr returns the field key of the entry.
+func (rec Record) Attr(key string) (interface{}, bool) {
+	v, ok := rec.Attrs[key]
+	return v, ok
+}
+
+func (r *Recorder) dump() string {
+	var b strings.Builder
+	for _, rec := range r.Records() {
+		b.WriteString("  " + rec.Level.String() + " " + rec.Message + "\n")
+	}
+
+	return b.String()
+}
+
+func (s *sink) Write(p []byte) (int, error) {
+	for _, line := range bytes.Split(bytes.TrimSpace(p), []byte("\n")) {
+		var fields map[string]interface{}
+		if err := json.Unmarshal(line, &fields); err != nil {
+			return 0, err
+		}
+
+		rec := newRecord(fields)
+
+		s.mu.Lock()
+		s.records = append(s.records, rec)
+		s.mu.Unlock()
+	}
+
+	return len(p), nil
+}
+
+func newRecord(fields map[string]interface{}) Record {
+	rec := Record{Attrs: map[string]interface{}{}}
+
+	for key, value := range fields {
+		s, _ := value.(string)
+
+		switch key {
+		case "severity":
+			rec.Level, _ = logging.ParseLevel(s)
+		case "message":
+			rec.Message = s
+		case "timestamp":
+			rec.Time, _ = time.Parse(time.RFC3339Nano, s)
+		case "logging.googleapis.com/trace":
+			rec.TraceID = s[strings.LastIndex(s, "/")+1:]
+		case "logging.googleapis.com/spanId":
+			rec.SpanID = s
+		case "@type":
+			rec.Reported = s == logging.ReportedErrorEventType
+		default:
+			rec.Attrs[key] = value
+		}
+	}
+
+	return rec
+}
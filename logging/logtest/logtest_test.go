@@ -0,0 +1,90 @@
+package logtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/propertechnologies/monitor/logging"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestThatRecorderCapturesStructuredRecords(t *testing.T) {
+	t.Parallel()
+
+	tid, _ := trace.TraceIDFromHex("0af7651916cd43dd8448eb211c80319c")
+	sid, _ := trace.SpanIDFromHex("b7ad6b7169203331")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: tid,
+		SpanID:  sid,
+	}))
+
+	rec := New()
+	ctx = logging.SetLogger(ctx, rec)
+
+	logging.Debugf(ctx, "starting")
+	logging.Warn(ctx, "slow bank", "account_id", "acc-1")
+	logging.ReportError(ctx, errors.New("login failed"))
+
+	records := rec.Records()
+	assert.Len(t, records, 3)
+
+	warn := rec.AssertLogged(t, slog.LevelWarn, "slow bank")
+	assert.Equal(t, "acc-1", warn.Attrs["account_id"])
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", warn.TraceID)
+	assert.Equal(t, "b7ad6b7169203331", warn.SpanID)
+	assert.False(t, warn.Reported)
+
+	assert.Len(t, rec.Reported(), 1)
+	assert.Len(t, rec.ByLevel(slog.LevelDebug), 1)
+	rec.AssertNotLogged(t, "hunter2")
+
+	rec.Reset()
+	assert.Empty(t, rec.Records())
+}
+
+func TestThatRecordersAreIsolatedInParallelTests(t *testing.T) {
+	for i := 0; i < 4; i++ {
+		i := i
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			t.Parallel()
+
+			rec := New()
+			ctx := logging.SetLogger(context.Background(), rec)
+
+			for j := 0; j < 20; j++ {
+				logging.Infof(ctx, "recorder %d", i)
+			}
+
+			assert.Len(t, rec.Filter(func(r Record) bool { return r.Message == fmt.Sprintf("recorder %d", i) }), 20)
+			assert.Len(t, rec.Records(), 20)
+		})
+	}
+}
+
+func TestThatRecorderKeepsEntriesAsWritten(t *testing.T) {
+	t.Parallel()
+
+	rec := New()
+	ctx := logging.SetLogger(context.Background(), rec)
+
+	logging.Info(ctx, "login", "email", "bob@example.com")
+
+	entry := rec.AssertLogged(t, slog.LevelInfo, "login")
+	assert.Equal(t, "bob@example.com", entry.Attrs["email"])
+	assert.NotContains(t, entry.Attrs, "logging.googleapis.com/sourceLocation")
+}
+
+func TestThatRedactionCanBeTurnedBackOn(t *testing.T) {
+	t.Parallel()
+
+	rec := New(logging.WithRedaction(logging.RedactionOptions{}))
+	ctx := logging.SetLogger(context.Background(), rec)
+
+	logging.Info(ctx, "login", "email", "bob@example.com")
+
+	assert.NotEqual(t, "bob@example.com", rec.Records()[0].Attrs["email"])
+}
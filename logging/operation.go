@@ -0,0 +1,90 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/propertechnologies/monitor/context_util"
+)
+
+type (
+	// operation groups the entries of a run in Cloud Logging.
+	// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#LogEntryOperation
+	operation struct {
+		id       string
+		producer string
+		start    time.Time
+	}
+
+	operationKey    struct{}
+	operationMarker struct{}
+)
+
+// StartOperation returns a context whose entries are grouped under the
+// operation id, and logs its first entry. The producer is the bot, or the
+// service, of ctx.
+func StartOperation(ctx context.Context, id string) context.Context {
+	producer := context_util.GetBotName(ctx)
+	if producer == "" {
+		producer = context_util.GetServiceName(ctx)
+	}
+
+	ctx = context.WithValue(ctx, operationKey{}, &operation{id: id, producer: producer, start: time.Now()})
+	logAttrs(context.WithValue(ctx, operationMarker{}, "first"), slog.LevelInfo, "operation started", "operation_id", id)
+
+	return ctx
+}
+
+// EndOperation logs the last entry of the operation of ctx, if any.
+func EndOperation(ctx context.Context) {
+	op, ok := ctx.Value(operationKey{}).(*operation)
+	if !ok {
+		return
+	}
+
+	logAttrs(context.WithValue(ctx, operationMarker{}, "last"), slog.LevelInfo, "operation ended",
+		"operation_id", op.id,
+		"duration", time.Since(op.start).String(),
+	)
+}
+
+// operationAttr returns the operation field of the entries logged with ctx.
+func operationAttr(ctx context.Context) (slog.Attr, bool) {
+	op, ok := ctx.Value(operationKey{}).(*operation)
+	if !ok {
+		return slog.Attr{}, false
+	}
+
+	attrs := []slog.Attr{slog.String("id", op.id)}
+	if op.producer != "" {
+		attrs = append(attrs, slog.String("producer", op.producer))
+	}
+
+	if marker, ok := ctx.Value(operationMarker{}).(string); ok {
+		attrs = append(attrs, slog.Bool(marker, true))
+	}
+
+	return slog.Attr{Key: "logging.googleapis.com/operation", Value: slog.GroupValue(attrs...)}, true
+}
+
+// labelsAttr returns the labels of the entries logged with ctx, to filter
+// the ones of a bot, environment or flow.
+func labelsAttr(ctx context.Context) (slog.Attr, bool) {
+	var labels []slog.Attr
+	for _, l := range []slog.Attr{
+		slog.String("bot", context_util.GetBotName(ctx)),
+		slog.String("env", context_util.GetEnv(ctx)),
+		slog.String("flow-id", context_util.GetFlowID(ctx)),
+	} {
+		if l.Value.String() != "" {
+			labels = append(labels, l)
+		}
+	}
+
+	if len(labels) == 0 {
+		return slog.Attr{}, false
+	}
+
+	return slog.Attr{Key: "logging.googleapis.com/labels", Value: slog.GroupValue(labels...)}, true
+}
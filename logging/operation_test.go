@@ -0,0 +1,68 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type operationEntry struct {
+	Message   string            `json:"message"`
+	Labels    map[string]string `json:"logging.googleapis.com/labels"`
+	Operation struct {
+		ID       string `json:"id"`
+		Producer string `json:"producer"`
+		First    bool   `json:"first"`
+		Last     bool   `json:"last"`
+	} `json:"logging.googleapis.com/operation"`
+}
+
+func TestThatOperationEntriesAreGroupedAndLabeled(t *testing.T) {
+	t.Setenv("LOG_FORMAT", "json")
+
+	b := &bytes.Buffer{}
+	ctx := context.WithValue(context.Background(), "botname", "santander")
+	ctx = context.WithValue(ctx, "env", "prod")
+	ctx = context.WithValue(ctx, "FlowID", "flow-1")
+	ctx = SetLogger(ctx, NewLoggerWithWriter(b))
+
+	ctx = StartOperation(ctx, "run-42")
+	Infof(ctx, "fetching movements")
+	EndOperation(ctx)
+
+	var entries []operationEntry
+	for _, l := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		var e operationEntry
+		assert.NoError(t, json.Unmarshal([]byte(l), &e))
+		entries = append(entries, e)
+	}
+	assert.Len(t, entries, 3)
+
+	for _, e := range entries {
+		assert.Equal(t, map[string]string{"bot": "santander", "env": "prod", "flow-id": "flow-1"}, e.Labels)
+		assert.Equal(t, "run-42", e.Operation.ID)
+		assert.Equal(t, "santander", e.Operation.Producer)
+	}
+
+	assert.Equal(t, "operation started", entries[0].Message)
+	assert.True(t, entries[0].Operation.First)
+	assert.False(t, entries[1].Operation.First || entries[1].Operation.Last)
+	assert.Equal(t, "operation ended", entries[2].Message)
+	assert.True(t, entries[2].Operation.Last)
+}
+
+func TestThatEntriesWithoutOperationHaveNoOperationField(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b))
+
+	Infof(ctx, "no operation")
+	EndOperation(ctx)
+
+	assert.NotContains(t, b.String(), "logging.googleapis.com/operation")
+	assert.NotContains(t, b.String(), "logging.googleapis.com/labels")
+	assert.Equal(t, 1, strings.Count(b.String(), "\n"))
+}
@@ -0,0 +1,92 @@
+package logging
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+)
+
+const levelEnv = "LOG_LEVEL"
+
+type (
+	// Option configures the logger built by NewLogger and NewLoggerWithWriter.
+	Option func(*options)
+
+	options struct {
+		level          slog.Leveler
+		projectID      string
+		sampling       *SamplingOptions
+		redaction      *RedactionOptions
+		format         Format
+		sinks          []writerSink
+		entryLimit     *EntryLimitOptions
+		otel           *OTelOptions
+		spanEvents     slog.Leveler
+		sourceLocation bool
+	}
+)
+
+// WithLevel sets the minimum level logged. It overrides LOG_LEVEL.
+func WithLevel(level slog.Leveler) Option {
+	return func(o *options) {
+		o.level = level
+	}
+}
+
+// WithoutSourceLocation doesn't look up the caller of the log calls to write
+// its file and line, which saves some time on hot paths.
+func WithoutSourceLocation() Option {
+	return func(o *options) {
+		o.sourceLocation = false
+	}
+}
+
+// WithSourceLocation writes the file and line of the log calls, which is the
+// default. It undoes WithoutSourceLocation.
+func WithSourceLocation() Option {
+	return func(o *options) {
+		o.sourceLocation = true
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		level:          levelFromEnv(),
+		redaction:      &RedactionOptions{},
+		format:         formatFromEnv(),
+		entryLimit:     &EntryLimitOptions{},
+		sourceLocation: true,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// levelFromEnv reads LOG_LEVEL (debug, info, warn or error), defaulting to
+// info.
+func levelFromEnv() slog.Level {
+	level, err := ParseLevel(os.Getenv(levelEnv))
+	if err != nil {
+		return slog.LevelInfo
+	}
+
+	return level
+}
+
+// ParseLevel parses a level name as accepted by slog, also accepting
+// "warning" as used by Cloud Logging.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+
+	s = strings.TrimSpace(s)
+	if strings.EqualFold(s, "warning") {
+		s = "warn"
+	}
+
+	err := level.UnmarshalText([]byte(s))
+
+	return level, err
+}
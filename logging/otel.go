@@ -0,0 +1,226 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutlog"
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/global"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// otelScope is the instrumentation scope of the emitted records.
+const otelScope = "github.com/propertechnologies/monitor/logging"
+
+type (
+	// OTelOptions configures the emission of the entries through the
+	// OpenTelemetry Logs API.
+	OTelOptions struct {
+		// Provider emits the records. Defaults to the global provider.
+		Provider otellog.LoggerProvider
+		// Only disables the JSON output, the entries are only emitted
+		// through OpenTelemetry.
+		Only bool
+	}
+
+	// otelHandler emits the records through an OpenTelemetry logger. The
+	// span context of the logging context is kept on the records.
+	otelHandler struct {
+		logger otellog.Logger
+		goas   []groupOrAttrs
+	}
+)
+
+// WithOTelLogs also emits the entries through OpenTelemetry, or only
+// through it with OTelOptions.Only. The context_util fields, labels,
+// operation and source location are kept as attributes.
+func WithOTelLogs(opts OTelOptions) Option {
+	return func(o *options) {
+		o.otel = &opts
+	}
+}
+
+// NewOTLPLoggerProvider returns a provider exporting in batches to the local
+// OTLP/HTTP collector at endpoint, e.g. "localhost:4318". Shut it down on
+// exit to export the pending records.
+func NewOTLPLoggerProvider(ctx context.Context, endpoint string) (*sdklog.LoggerProvider, error) {
+	exporter, err := otlploghttp.New(ctx, otlploghttp.WithEndpoint(endpoint), otlploghttp.WithInsecure())
+	if err != nil {
+		return nil, err
+	}
+
+	return sdklog.NewLoggerProvider(
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter)),
+		sdklog.WithResource(resource.Default()),
+	), nil
+}
+
+// NewFileLoggerProvider returns a provider writing the records as JSON lines
+// to w, such as a RotatingFile. Shut it down on exit to write the pending
+// records.
+func NewFileLoggerProvider(w io.Writer) (*sdklog.LoggerProvider, error) {
+	exporter, err := stdoutlog.New(stdoutlog.WithWriter(w))
+	if err != nil {
+		return nil, err
+	}
+
+	return sdklog.NewLoggerProvider(
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter)),
+		sdklog.WithResource(resource.Default()),
+	), nil
+}
+
+func newOTelHandler(opts OTelOptions) *otelHandler {
+	provider := opts.Provider
+	if provider == nil {
+		provider = global.GetLoggerProvider()
+	}
+
+	return &otelHandler{logger: provider.Logger(otelScope)}
+}
+
+func (h *otelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	var record otellog.Record
+	record.SetSeverity(otelSeverity(level))
+
+	return h.logger.Enabled(ctx, record)
+}
+
+func (h *otelHandler) Handle(ctx context.Context, r slog.Record) error {
+	var record otellog.Record
+	record.SetTimestamp(r.Time)
+	record.SetSeverity(otelSeverity(r.Level))
+	record.SetSeverityText(severity(r.Level))
+	record.SetBody(otellog.StringValue(r.Message))
+
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		if strings.HasPrefix(a.Key, protectedPrefix) {
+			record.AddAttributes(otelSpecialKeyValues(a)...)
+		} else {
+			attrs = append(attrs, a)
+		}
+		return true
+	})
+
+	for _, a := range nestAttrs(h.goas, attrs) {
+		record.AddAttributes(otelKeyValue(a))
+	}
+
+	// The span context may only be in the traceContext value, see
+	// getSpanContext.
+	if s := getSpanContext(ctx); s.IsValid() {
+		ctx = trace.ContextWithSpanContext(ctx, s)
+	}
+
+	h.logger.Emit(ctx, record)
+
+	return nil
+}
+
+func (h *otelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	return &otelHandler{logger: h.logger, goas: append(append([]groupOrAttrs{}, h.goas...), groupOrAttrs{attrs: attrs})}
+}
+
+func (h *otelHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	return &otelHandler{logger: h.logger, goas: append(append([]groupOrAttrs{}, h.goas...), groupOrAttrs{group: name})}
+}
+
+// otelSeverity maps the slog levels to the OpenTelemetry ones, which are
+// offset by 9.
+func otelSeverity(level slog.Level) otellog.Severity {
+	return otellog.Severity(level + 9)
+}
+
+// otelSpecialKeyValues maps the Cloud Logging fields to attributes. The
+// source location uses the code.* semantic conventions, and the trace fields
+// are carried by the record itself.
+func otelSpecialKeyValues(a slog.Attr) []otellog.KeyValue {
+	name := strings.TrimPrefix(a.Key, protectedPrefix)
+
+	var kvs []otellog.KeyValue
+	switch name {
+	case "labels", "operation":
+		for _, field := range a.Value.Resolve().Group() {
+			kvs = append(kvs, otellog.KeyValue{Key: name + "." + field.Key, Value: otelValue(field.Value)})
+		}
+	case "sourceLocation":
+		for _, field := range a.Value.Resolve().Group() {
+			switch field.Key {
+			case "file":
+				kvs = append(kvs, otellog.String("code.filepath", field.Value.String()))
+			case "line":
+				if line, err := strconv.Atoi(field.Value.String()); err == nil {
+					kvs = append(kvs, otellog.Int("code.lineno", line))
+				}
+			case "function":
+				kvs = append(kvs, otellog.String("code.function", field.Value.String()))
+			}
+		}
+	}
+
+	return kvs
+}
+
+func otelKeyValue(a slog.Attr) otellog.KeyValue {
+	return otellog.KeyValue{Key: a.Key, Value: otelValue(a.Value)}
+}
+
+func otelValue(v slog.Value) otellog.Value {
+	v = v.Resolve()
+
+	switch v.Kind() {
+	case slog.KindString:
+		return otellog.StringValue(v.String())
+	case slog.KindInt64:
+		return otellog.Int64Value(v.Int64())
+	case slog.KindUint64:
+		if v.Uint64() > math.MaxInt64 {
+			return otellog.StringValue(strconv.FormatUint(v.Uint64(), 10))
+		}
+		return otellog.Int64Value(int64(v.Uint64()))
+	case slog.KindFloat64:
+		return otellog.Float64Value(v.Float64())
+	case slog.KindBool:
+		return otellog.BoolValue(v.Bool())
+	case slog.KindDuration:
+		return otellog.StringValue(v.Duration().String())
+	case slog.KindTime:
+		return otellog.StringValue(v.Time().Format(time.RFC3339Nano))
+	case slog.KindGroup:
+		kvs := make([]otellog.KeyValue, 0, len(v.Group()))
+		for _, a := range v.Group() {
+			kvs = append(kvs, otelKeyValue(a))
+		}
+		return otellog.MapValue(kvs...)
+	}
+
+	switch x := v.Any().(type) {
+	case error:
+		return otellog.StringValue(x.Error())
+	case []byte:
+		return otellog.BytesValue(x)
+	case fmt.Stringer:
+		return otellog.StringValue(x.String())
+	}
+
+	return otellog.StringValue(attrString(v))
+}
@@ -0,0 +1,81 @@
+package logging
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/propertechnologies/monitor/context_util"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestThatEntriesAreEmittedThroughOTel(t *testing.T) {
+	b := &syncBuffer{}
+	provider, err := NewFileLoggerProvider(b)
+	assert.NoError(t, err)
+
+	tid, _ := trace.TraceIDFromHex("0af7651916cd43dd8448eb211c80319c")
+	sid, _ := trace.SpanIDFromHex("b7ad6b7169203331")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: tid,
+		SpanID:  sid,
+	}))
+	ctx = context_util.SetServiceName(ctx, "payroll")
+
+	out := &syncBuffer{}
+	ctx = SetLogger(ctx, NewLoggerWithWriter(out, WithOTelLogs(OTelOptions{Provider: provider, Only: true})))
+
+	Warn(With(ctx, "account_id", "acc-1"), "balance mismatch", "diff", 12.5)
+
+	assert.NoError(t, provider.Shutdown(context.Background()))
+	assert.Empty(t, out.String())
+
+	s := b.String()
+	assert.Contains(t, s, `"Body":{"Type":"String","Value":"balance mismatch"}`)
+	assert.Contains(t, s, `"SeverityText":"WARNING"`)
+	assert.Contains(t, s, `"TraceID":"0af7651916cd43dd8448eb211c80319c"`)
+	assert.Contains(t, s, `"SpanID":"b7ad6b7169203331"`)
+	assert.Contains(t, s, `{"Key":"app","Value":{"Type":"String","Value":"payroll"}}`)
+	assert.Contains(t, s, `{"Key":"account_id","Value":{"Type":"String","Value":"acc-1"}}`)
+	assert.Contains(t, s, `{"Key":"diff","Value":{"Type":"Float64","Value":12.5}}`)
+	assert.NotContains(t, s, "logging.googleapis.com")
+}
+
+func TestThatOTelIsEmittedAlongsideJSON(t *testing.T) {
+	b := &syncBuffer{}
+	provider, err := NewFileLoggerProvider(b)
+	assert.NoError(t, err)
+
+	out := &syncBuffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(out, WithOTelLogs(OTelOptions{Provider: provider})))
+
+	Infof(ctx, "synced")
+
+	assert.NoError(t, provider.Shutdown(context.Background()))
+	assert.Contains(t, out.String(), `"message":"synced"`)
+	assert.Contains(t, b.String(), `"Value":"synced"`)
+}
+
+func TestThatCloudLoggingFieldsAreMappedToOTelAttributes(t *testing.T) {
+	b := &syncBuffer{}
+	provider, err := NewFileLoggerProvider(b)
+	assert.NoError(t, err)
+
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(&syncBuffer{}, WithOTelLogs(OTelOptions{Provider: provider, Only: true})))
+	ctx = context.WithValue(ctx, "botname", "payroll")
+	ctx = StartOperation(ctx, "run-1")
+
+	Info(ctx, "synced", "count", uint64(math.MaxUint64))
+
+	assert.NoError(t, provider.Shutdown(context.Background()))
+
+	s := b.String()
+	assert.Contains(t, s, `{"Key":"labels.bot","Value":{"Type":"String","Value":"payroll"}}`)
+	assert.Contains(t, s, `{"Key":"operation.id","Value":{"Type":"String","Value":"run-1"}}`)
+	assert.Contains(t, s, `{"Key":"code.filepath","Value":{"Type":"String","Value":"`)
+	assert.Contains(t, s, `{"Key":"code.lineno","Value":{"Type":"Int64","Value":`)
+	assert.Contains(t, s, `{"Key":"code.function","Value":{"Type":"String","Value":"github.com/propertechnologies/monitor/logging.TestThatCloudLoggingFieldsAreMappedToOTelAttributes"}}`)
+	assert.Contains(t, s, `{"Key":"count","Value":{"Type":"String","Value":"18446744073709551615"}}`)
+	assert.NotContains(t, s, "logging.googleapis.com")
+}
@@ -0,0 +1,91 @@
+package logging
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/compute/metadata"
+)
+
+// localProjectID stands in for the project when running outside GCP, trace
+// links are meaningless there anyway.
+const localProjectID = "local"
+
+var (
+	projectIDOnce sync.Once
+	projectID     string
+	// projectIDDone is closed once projectID is detected.
+	projectIDDone = make(chan struct{})
+)
+
+// WithProjectID sets the GCP project used to build the
+// logging.googleapis.com/trace field. By default it's detected, see
+// DetectProjectID.
+func WithProjectID(id string) Option {
+	return func(o *options) {
+		o.projectID = id
+	}
+}
+
+// DetectProjectID returns the project from GCP_PROJECT_ID or
+// GOOGLE_CLOUD_PROJECT, then from the metadata server, falling back to a
+// local stand-in. The result is computed once.
+func DetectProjectID() string {
+	projectIDOnce.Do(func() {
+		projectID = detectProjectID()
+		close(projectIDDone)
+	})
+
+	return projectID
+}
+
+// startProjectDetection detects the project in the background, so that log
+// calls never wait for the metadata server.
+func startProjectDetection() {
+	go DetectProjectID()
+}
+
+// detectedProjectID returns the detected project without waiting for the
+// detection, using the environment or the local stand-in until it's done.
+func detectedProjectID() string {
+	select {
+	case <-projectIDDone:
+		return projectID
+	default:
+	}
+
+	if id := envProjectID(); id != "" {
+		return id
+	}
+
+	return localProjectID
+}
+
+func envProjectID() string {
+	for _, env := range []string{"GCP_PROJECT_ID", "GOOGLE_CLOUD_PROJECT"} {
+		if id := os.Getenv(env); id != "" {
+			return id
+		}
+	}
+
+	return ""
+}
+
+func detectProjectID() string {
+	if id := envProjectID(); id != "" {
+		return id
+	}
+
+	if metadata.OnGCE() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		if id, err := metadata.ProjectIDWithContext(ctx); err == nil && id != "" {
+			return id
+		}
+	}
+
+	return localProjectID
+}
@@ -0,0 +1,144 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+
+	"go.opentelemetry.io/otel/codes"
+)
+
+type (
+	RecoverOption func(*recoverOptions)
+
+	recoverOptions struct {
+		repanic bool
+	}
+
+	// valuesContext looks up values in its own context first and then in
+	// base, so request contexts can see the logger and service name of the
+	// server context.
+	valuesContext struct {
+		context.Context
+		base context.Context
+	}
+)
+
+// Repanic panics again with the same value once the panic was reported.
+func Repanic() RecoverOption {
+	return func(o *recoverOptions) {
+		o.repanic = true
+	}
+}
+
+// Recover reports a panic of the current goroutine through Reportf and
+// records it on the active span. It must be deferred directly:
+//
+//	defer logging.Recover(ctx)
+func Recover(ctx context.Context, opts ...RecoverOption) {
+	if v := recover(); v != nil {
+		handlePanic(ctx, v, opts)
+	}
+}
+
+// Go runs fn in a new goroutine whose panics are recovered and reported.
+func Go(ctx context.Context, fn func(context.Context), opts ...RecoverOption) {
+	go func() {
+		defer Recover(ctx, opts...)
+
+		fn(ctx)
+	}()
+}
+
+// RecoverMiddleware recovers panics of next, reports them and answers 500
+// unless the response was already started.
+// Requests see the values of ctx, such as its logger, unless they carry
+// their own.
+func RecoverMiddleware(ctx context.Context, next http.Handler, opts ...RecoverOption) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				// Used by net/http to abort a response, not a failure.
+				panic(v)
+			}
+
+			handlePanic(withValuesFrom(r.Context(), ctx), v, opts)
+
+			// Once the response started, a 500 would only be appended to it.
+			if !rec.wroteHeader {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}()
+
+		next.ServeHTTP(rec, r)
+	})
+}
+
+func handlePanic(ctx context.Context, v interface{}, opts []RecoverOption) {
+	o := &recoverOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	r := newErrorReport(panicFrames())
+	r.stack = fmt.Sprintf("panic: %v\n\n%s", v, r.stack)
+	r.recorded = true
+
+	span := spanFromContext(ctx)
+	span.RecordError(fmt.Errorf("panic: %v", v))
+	span.SetStatus(codes.Error, fmt.Sprintf("panic: %v", v))
+
+	reportf(ctx, r, "panic: %v", v)
+
+	if o.repanic {
+		panic(v)
+	}
+}
+
+// panicFrames returns the stack starting at the frame that panicked.
+func panicFrames() []runtime.Frame {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(1, pcs)
+	frames := framesOf(pcs[:n])
+
+	for i, f := range frames {
+		if f.Function != "runtime.gopanic" {
+			continue
+		}
+
+		// Runtime errors go through runtime.panicmem, sigpanic or
+		// goPanicIndex, start at the frame that faulted instead.
+		frames = frames[i+1:]
+		for len(frames) > 1 && strings.HasPrefix(frames[0].Function, "runtime.") {
+			frames = frames[1:]
+		}
+
+		return frames
+	}
+
+	return callerFrames()
+}
+
+func withValuesFrom(ctx, base context.Context) context.Context {
+	if base == nil {
+		return ctx
+	}
+
+	return &valuesContext{Context: ctx, base: base}
+}
+
+func (v *valuesContext) Value(key interface{}) interface{} {
+	if value := v.Context.Value(key); value != nil {
+		return value
+	}
+
+	return v.base.Value(key)
+}
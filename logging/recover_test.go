@@ -0,0 +1,134 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func panicking() {
+	panic("boom")
+}
+
+func TestThatRecoverReportsThePanicAndRecordsItOnTheSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b))
+	ctx, span := tp.Tracer("test").Start(ctx, "bot")
+
+	func() {
+		defer Recover(ctx)
+		panicking()
+	}()
+	span.End()
+
+	var entry reportedEntry
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &entry))
+	assert.True(t, strings.HasPrefix(entry.StackTrace, "panic: boom\n\ngoroutine "))
+	assert.Contains(t, entry.Context.ReportLocation.FunctionName, "logging.panicking")
+	assert.Contains(t, b.String(), `"message":"panic: boom"`)
+
+	ended := recorder.Ended()
+	assert.Len(t, ended, 1)
+	assert.Equal(t, codes.Error, ended[0].Status().Code)
+	assert.Len(t, ended[0].Events(), 1)
+}
+
+type account struct {
+	id string
+}
+
+func dereferencing(a *account) string {
+	return a.id
+}
+
+func TestThatRuntimePanicsAreReportedAtTheFaultingFrame(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b))
+
+	func() {
+		defer Recover(ctx)
+		dereferencing(nil)
+	}()
+
+	var entry reportedEntry
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &entry))
+	assert.Contains(t, entry.Context.ReportLocation.FunctionName, "logging.dereferencing")
+	assert.True(t, strings.HasSuffix(entry.Context.ReportLocation.FilePath, "recover_test.go"))
+
+	lines := strings.Split(entry.StackTrace, "\n")
+	assert.Contains(t, lines[3], "logging.dereferencing")
+}
+
+func TestThatRepanicPanicsAgainAfterReporting(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b))
+
+	assert.PanicsWithValue(t, "boom", func() {
+		defer Recover(ctx, Repanic())
+		panicking()
+	})
+	assert.Contains(t, b.String(), "panic: boom")
+}
+
+func TestThatGoRecoversPanicsOfTheGoroutine(t *testing.T) {
+	b := &syncBuffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	Go(ctx, func(ctx context.Context) {
+		defer wg.Done()
+		panicking()
+	})
+	wg.Wait()
+
+	assert.Eventually(t, func() bool {
+		return strings.Contains(b.String(), "panic: boom")
+	}, time.Second, time.Millisecond)
+}
+
+func TestThatRecoverMiddlewareAnswers500(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b))
+
+	handler := RecoverMiddleware(ctx, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panicking()
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, b.String(), ReportedErrorEventType)
+}
+
+func TestThatRecoverMiddlewareKeepsStartedResponses(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b))
+
+	handler := RecoverMiddleware(ctx, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		panicking()
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "partial", w.Body.String())
+	assert.Contains(t, b.String(), ReportedErrorEventType)
+}
@@ -0,0 +1,387 @@
+package logging
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const redactionSaltEnv = "LOG_REDACTION_SALT"
+
+var (
+	defaultRedactedKeys = []string{
+		"password",
+		"passwd",
+		"secret",
+		"token",
+		"access_token",
+		"refresh_token",
+		"client_secret",
+		"api_key",
+		"apikey",
+		"authorization",
+		"cookie",
+	}
+
+	defaultDetectors = []detector{
+		{name: "bearer", re: regexp.MustCompile(`(?i)(\bbearer\s+)([A-Za-z0-9\-._~+/]+=*)`), group: 2},
+		{name: "email", re: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)},
+		{name: "iban", re: regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]){11,30}\b`), valid: validIBAN, separators: " "},
+		{name: "card", re: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), valid: validLuhn, separators: " -"},
+		{name: "ssn", re: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)},
+	}
+
+	// ibanLengths are the IBAN lengths of the ISO 13616 registry.
+	ibanLengths = map[string]int{
+		"AD": 24, "AE": 23, "AL": 28, "AT": 20, "AZ": 28, "BA": 20, "BE": 16, "BG": 22,
+		"BH": 22, "BI": 27, "BR": 29, "BY": 28, "CH": 21, "CR": 22, "CY": 28, "CZ": 24,
+		"DE": 22, "DJ": 27, "DK": 18, "DO": 28, "EE": 20, "EG": 29, "ES": 24, "FI": 18,
+		"FK": 18, "FO": 18, "FR": 27, "GB": 22, "GE": 22, "GI": 23, "GL": 18, "GR": 27,
+		"GT": 28, "HR": 21, "HU": 28, "IE": 22, "IL": 23, "IQ": 23, "IS": 26, "IT": 27,
+		"JO": 30, "KW": 30, "KZ": 20, "LB": 28, "LC": 32, "LI": 21, "LT": 20, "LU": 20,
+		"LV": 21, "LY": 25, "MC": 27, "MD": 24, "ME": 22, "MK": 19, "MN": 20, "MR": 27,
+		"MT": 31, "MU": 30, "NI": 28, "NL": 18, "NO": 15, "OM": 23, "PK": 24, "PL": 28,
+		"PS": 29, "PT": 25, "QA": 29, "RO": 24, "RS": 22, "RU": 33, "SA": 24, "SC": 31,
+		"SD": 18, "SE": 24, "SI": 19, "SK": 24, "SM": 27, "SO": 23, "ST": 25, "SV": 28,
+		"TL": 23, "TN": 24, "TR": 26, "UA": 29, "VA": 22, "VG": 24, "XK": 20, "YE": 30,
+	}
+)
+
+type (
+	// RedactionOptions extends the built-in redaction of bearer tokens, card
+	// numbers, IBANs, emails and SSN-like values.
+	RedactionOptions struct {
+		// Patterns are additional expressions whose matches are redacted.
+		Patterns map[string]*regexp.Regexp
+		// Keys are additional attribute keys whose values are always
+		// redacted.
+		Keys []string
+		// Salt keys the hashes that replace the redacted values. Defaults
+		// to LOG_REDACTION_SALT, or to one derived from the project so
+		// hashes correlate across instances and runs. Set a secret salt
+		// to keep short values from being guessed from their hash.
+		Salt string
+	}
+
+	// redactor replaces sensitive values by a stable hash, so the same value
+	// can still be correlated across entries.
+	redactor struct {
+		detectors []detector
+		keys      map[string]bool
+		salt      []byte
+	}
+
+	detector struct {
+		name  string
+		re    *regexp.Regexp
+		group int
+		valid func(string) bool
+		// separators split a match failing valid into the shorter
+		// candidates to check, as greedy matches take the next words.
+		separators string
+	}
+)
+
+// WithRedaction adds patterns and keys to the built-in redaction.
+func WithRedaction(opts RedactionOptions) Option {
+	return func(o *options) {
+		o.redaction = &opts
+	}
+}
+
+// WithoutRedaction disables the redaction of log output.
+func WithoutRedaction() Option {
+	return func(o *options) {
+		o.redaction = nil
+	}
+}
+
+func newRedactor(opts RedactionOptions, projectID string) *redactor {
+	r := &redactor{
+		detectors: append([]detector{}, defaultDetectors...),
+		keys:      map[string]bool{},
+		salt:      []byte(opts.Salt),
+	}
+
+	if len(r.salt) == 0 {
+		r.salt = []byte(os.Getenv(redactionSaltEnv))
+	}
+	if len(r.salt) == 0 {
+		r.salt = projectSalt(projectID)
+	}
+
+	for name, re := range opts.Patterns {
+		r.detectors = append(r.detectors, detector{name: name, re: re})
+	}
+
+	for _, k := range append(append([]string{}, defaultRedactedKeys...), opts.Keys...) {
+		r.keys[strings.ToLower(k)] = true
+	}
+
+	return r
+}
+
+func (r *redactor) String(s string) string {
+	for _, d := range r.detectors {
+		s = d.replace(s, r.hash)
+	}
+
+	return s
+}
+
+func (r *redactor) Attrs(attrs []slog.Attr) []slog.Attr {
+	redacted := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		redacted = append(redacted, r.Attr(a))
+	}
+
+	return redacted
+}
+
+func (r *redactor) Attr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+
+	if r.keys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, r.hash("key", v.String()))
+	}
+
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, r.String(v.String()))
+	case slog.KindGroup:
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(r.Attrs(v.Group())...)}
+	case slog.KindAny:
+		switch x := v.Any().(type) {
+		case error:
+			return slog.String(a.Key, r.String(x.Error()))
+		case []byte:
+			if s := r.String(string(x)); s != string(x) {
+				return slog.String(a.Key, s)
+			}
+		default:
+			if !mayHoldStrings(x) {
+				break
+			}
+			if redacted, ok := r.any(x); ok {
+				return slog.Any(a.Key, redacted)
+			}
+		}
+	}
+
+	return slog.Attr{Key: a.Key, Value: v}
+}
+
+// mayHoldStrings tells whether v may hold strings. The values which can't,
+// such as numbers, byte arrays or times, are written as is.
+func mayHoldStrings(v interface{}) bool {
+	switch v.(type) {
+	case nil, time.Time, time.Duration, slog.Level:
+		return false
+	}
+
+	t := reflect.TypeOf(v)
+	if k := t.Kind(); k == reflect.Array || k == reflect.Slice || k == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return false
+	}
+
+	return true
+}
+
+// any redacts the JSON form of v, the one written to the entries. It
+// reports false when nothing had to be redacted.
+func (r *redactor) any(v interface{}) (interface{}, bool) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		s := fmt.Sprint(v)
+		redacted := r.String(s)
+
+		return redacted, redacted != s
+	}
+
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+
+	var decoded interface{}
+	if err := d.Decode(&decoded); err != nil {
+		return nil, false
+	}
+
+	return r.value(decoded)
+}
+
+func (r *redactor) value(v interface{}) (interface{}, bool) {
+	changed := false
+
+	switch x := v.(type) {
+	case string:
+		s := r.String(x)
+		return s, s != x
+	case map[string]interface{}:
+		for k, e := range x {
+			if r.keys[strings.ToLower(k)] {
+				x[k] = r.hash("key", fmt.Sprint(e))
+				changed = true
+				continue
+			}
+
+			if redacted, ok := r.value(e); ok {
+				x[k] = redacted
+				changed = true
+			}
+		}
+	case []interface{}:
+		for i, e := range x {
+			if redacted, ok := r.value(e); ok {
+				x[i] = redacted
+				changed = true
+			}
+		}
+	}
+
+	return v, changed
+}
+
+func (r *redactor) hash(kind, value string) string {
+	mac := hmac.New(sha256.New, r.salt)
+	mac.Write([]byte(value))
+
+	return "[REDACTED:" + kind + ":" + hex.EncodeToString(mac.Sum(nil))[:12] + "]"
+}
+
+func (d detector) replace(s string, hash func(kind, value string) string) string {
+	return d.re.ReplaceAllStringFunc(s, func(match string) string {
+		if d.group == 0 {
+			if d.valid == nil || d.valid(match) {
+				return hash(d.name, match)
+			}
+
+			start, end, ok := d.shorter(match)
+			if !ok {
+				return match
+			}
+
+			return match[:start] + hash(d.name, match[start:end]) + match[end:]
+		}
+
+		sub := d.re.FindStringSubmatchIndex(match)
+		start, end := sub[2*d.group], sub[2*d.group+1]
+
+		return match[:start] + hash(d.name, match[start:end]) + match[end:]
+	})
+}
+
+// shorter returns the longest, then leftmost, valid part of match that
+// starts and ends at separators.
+func (d detector) shorter(match string) (int, int, bool) {
+	starts, ends := []int{0}, []int{}
+	for i, c := range match {
+		if strings.ContainsRune(d.separators, c) {
+			starts = append(starts, i+1)
+			ends = append(ends, i)
+		}
+	}
+	ends = append(ends, len(match))
+
+	best, bestStart, bestEnd := 0, 0, 0
+	for _, start := range starts {
+		for _, end := range ends {
+			if end-start > best && d.valid(match[start:end]) {
+				best, bestStart, bestEnd = end-start, start, end
+			}
+		}
+	}
+
+	return bestStart, bestEnd, best != 0
+}
+
+// projectSalt returns the salt used when none is configured, the same for
+// every instance of the project.
+func projectSalt(projectID string) []byte {
+	if projectID == "" {
+		projectID = envProjectID()
+	}
+	if projectID == "" {
+		projectID = localProjectID
+	}
+
+	return []byte("propertechnologies/monitor:" + projectID)
+}
+
+func digits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// validLuhn checks the card number checksum, so that other long numbers
+// such as amounts or IDs are kept.
+func validLuhn(s string) bool {
+	d := digits(s)
+	if len(d) < 13 || len(d) > 19 {
+		return false
+	}
+
+	sum := 0
+	for i := len(d) - 1; i >= 0; i-- {
+		n := int(d[i] - '0')
+		if (len(d)-i)%2 == 0 {
+			n *= 2
+			if n > 9 {
+				n -= 9
+			}
+		}
+		sum += n
+	}
+
+	return sum%10 == 0
+}
+
+// validIBAN checks the length of the country and the ISO 13616 mod-97
+// checksum.
+func validIBAN(s string) bool {
+	s = strings.ReplaceAll(s, " ", "")
+	if len(s) < 15 || len(s) > 34 {
+		return false
+	}
+	if n, ok := ibanLengths[s[:2]]; ok && len(s) != n {
+		return false
+	}
+
+	var b strings.Builder
+	for _, c := range s[4:] + s[:4] {
+		switch {
+		case c >= '0' && c <= '9':
+			b.WriteRune(c)
+		case c >= 'A' && c <= 'Z':
+			b.WriteString(strconv.Itoa(int(c-'A') + 10))
+		default:
+			return false
+		}
+	}
+
+	n, ok := new(big.Int).SetString(b.String(), 10)
+
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
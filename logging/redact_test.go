@@ -0,0 +1,143 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThatBuiltInDetectorsRedactTheMessage(t *testing.T) {
+	r := newRedactor(RedactionOptions{Salt: "salt"}, "")
+
+	var cases = []struct {
+		name     string
+		input    string
+		redacted string
+	}{
+		{name: "bearer token", input: "header Authorization: Bearer abc.def-ghi", redacted: "abc.def-ghi"},
+		{name: "card number", input: "paid with 4111 1111 1111 1111 today", redacted: "4111 1111 1111 1111"},
+		{name: "iban", input: "to GB82 WEST 1234 5698 7654 32", redacted: "GB82 WEST 1234 5698 7654 32"},
+		{name: "email", input: "user bob.smith@example.com logged in", redacted: "bob.smith@example.com"},
+		{name: "ssn", input: "ssn 123-45-6789", redacted: "123-45-6789"},
+		{name: "iban followed by a word", input: "to DE89 3704 0044 0532 0130 00 EUR", redacted: "3704 0044 0532 0130"},
+		{name: "compact iban followed by a word", input: "DE89370400440532013000 OK", redacted: "DE89370400440532013000"},
+		{name: "card followed by a number", input: "card 4111111111111111 2 times", redacted: "4111111111111111"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			out := r.String(c.input)
+
+			assert.NotContains(t, out, c.redacted)
+			assert.Contains(t, out, "[REDACTED:")
+		})
+	}
+}
+
+func TestThatNumbersFailingChecksumsAreKept(t *testing.T) {
+	r := newRedactor(RedactionOptions{}, "")
+
+	assert.Equal(t, "amount 4111111111111112", r.String("amount 4111111111111112"))
+	assert.Equal(t, "ref GB00WEST12345698765432", r.String("ref GB00WEST12345698765432"))
+}
+
+func TestThatOnlyTheValidPartOfAMatchIsRedacted(t *testing.T) {
+	r := newRedactor(RedactionOptions{Salt: "salt"}, "")
+
+	assert.Regexp(t, `^card \[REDACTED:card:\w+\] 2 times$`, r.String("card 4111111111111111 2 times"))
+	assert.Regexp(t, `^to \[REDACTED:iban:\w+\] EUR$`, r.String("to DE89 3704 0044 0532 0130 00 EUR"))
+}
+
+func TestThatHashesAreSaltedByProjectWhenNoSaltIsSet(t *testing.T) {
+	t.Setenv(redactionSaltEnv, "")
+
+	unsalted := hmac.New(sha256.New, nil)
+	unsalted.Write([]byte("bob@example.com"))
+
+	out := newRedactor(RedactionOptions{}, "ledgerlord").String("bob@example.com")
+
+	assert.Equal(t, out, newRedactor(RedactionOptions{}, "ledgerlord").String("bob@example.com"))
+	assert.NotEqual(t, out, newRedactor(RedactionOptions{}, "payroll").String("bob@example.com"))
+	assert.NotContains(t, out, hex.EncodeToString(unsalted.Sum(nil))[:12])
+}
+
+func TestThatOnlyValuesHoldingStringsAreScanned(t *testing.T) {
+	assert.False(t, mayHoldStrings(42))
+	assert.False(t, mayHoldStrings([8]byte{}))
+	assert.False(t, mayHoldStrings([]float64{1.5}))
+	assert.False(t, mayHoldStrings(time.Now()))
+	assert.True(t, mayHoldStrings(map[string]int{}))
+	assert.True(t, mayHoldStrings([]string{"4111111111111111"}))
+	assert.True(t, mayHoldStrings(struct{ Card string }{}))
+}
+
+func TestThatRedactedValuesHaveStableHashes(t *testing.T) {
+	r := newRedactor(RedactionOptions{Salt: "salt"}, "")
+
+	assert.Equal(t, r.String("bob@example.com"), r.String("bob@example.com"))
+	assert.NotEqual(t, r.String("bob@example.com"), r.String("alice@example.com"))
+}
+
+func TestThatLoggerRedactsMessagesAttributesAndKeys(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b, WithRedaction(RedactionOptions{
+		Patterns: map[string]*regexp.Regexp{"account": regexp.MustCompile(`ACC-\d+`)},
+		Keys:     []string{"otp"},
+	})))
+
+	ctx = With(ctx, "password", "hunter2")
+	Info(ctx, "login for bob@example.com on ACC-123",
+		"otp", "123456",
+		"err", errors.New("rejected card 4111111111111111"),
+		"account_id", "acc-1",
+	)
+
+	assert.NotContains(t, b.String(), "hunter2")
+	assert.NotContains(t, b.String(), "bob@example.com")
+	assert.NotContains(t, b.String(), "ACC-123")
+	assert.NotContains(t, b.String(), "123456")
+	assert.NotContains(t, b.String(), "4111111111111111")
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &entry))
+	assert.Equal(t, "acc-1", entry["account_id"])
+	assert.Contains(t, entry["password"], "[REDACTED:key:")
+}
+
+func TestThatOtherValuesAreRedacted(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b))
+
+	Info(ctx, "payment",
+		"payload", map[string]interface{}{"card": "4111111111111111", "meta": map[string]string{"token": "t0k3n"}},
+		"body", []byte(`{"email":"bob@example.com"}`),
+		"amount", struct{ Value int }{Value: 42},
+	)
+
+	assert.NotContains(t, b.String(), "4111111111111111")
+	assert.NotContains(t, b.String(), "t0k3n")
+	assert.NotContains(t, b.String(), "bob@example.com")
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &entry))
+	assert.Contains(t, entry["payload"].(map[string]interface{})["card"], "[REDACTED:card:")
+	assert.Equal(t, map[string]interface{}{"Value": float64(42)}, entry["amount"])
+}
+
+func TestThatRedactionCanBeDisabled(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b, WithoutRedaction()))
+
+	Infof(ctx, "user %s", "bob@example.com")
+
+	assert.Contains(t, b.String(), "bob@example.com")
+}
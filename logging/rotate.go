@@ -0,0 +1,309 @@
+package logging
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultMaxFileSize = 100 << 20
+	backupTimeFormat   = "20060102T150405.000000000"
+	// rotationRetryDelay is waited before rotating again after a failure.
+	rotationRetryDelay = 10 * time.Second
+)
+
+type (
+	RotationOptions struct {
+		// MaxSize is the size in bytes after which the file is rotated.
+		// Defaults to 100MB.
+		MaxSize int64
+		// MaxAge rotates the file once it has been open for that long. Zero
+		// disables rotation by age.
+		MaxAge time.Duration
+		// MaxBackups is the number of rotated files to keep, zero keeps all.
+		MaxBackups int
+		// Compress gzips the rotated files.
+		Compress bool
+	}
+
+	// RotatingFile is a writer for NewLoggerWithWriter appending to a file,
+	// which is rotated by size and age. It's reopened on SIGHUP, so external
+	// tools like logrotate can move it as well.
+	RotatingFile struct {
+		path string
+		opts RotationOptions
+
+		mu       sync.Mutex
+		file     *os.File
+		size     int64
+		openedAt time.Time
+		// retryAt is when a failed rotation may be attempted again.
+		retryAt time.Time
+
+		// mill runs the compression and cleanup of the rotated files.
+		mill    sync.WaitGroup
+		millMu  sync.Mutex
+		signals chan os.Signal
+	}
+)
+
+func NewRotatingFile(path string, opts RotationOptions) (*RotatingFile, error) {
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = defaultMaxFileSize
+	}
+
+	f := &RotatingFile{path: path, opts: opts, signals: make(chan os.Signal, 1)}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	signal.Notify(f.signals, syscall.SIGHUP)
+	go f.reopenOnSignal()
+
+	return f, nil
+}
+
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, ErrWriterClosed
+	}
+
+	if f.size > 0 && (f.size+int64(len(p)) > f.opts.MaxSize || f.expired()) && !time.Now().Before(f.retryAt) {
+		if err := f.rotate(); err != nil {
+			// Keep writing to the open file rather than losing the entries
+			// until the rotation works again.
+			f.retryAt = time.Now().Add(rotationRetryDelay)
+			fmt.Fprintf(os.Stderr, "logging: rotating %s: %v\n", f.path, err)
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+
+	return n, err
+}
+
+// Rotate moves the current file aside and starts a new one.
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return ErrWriterClosed
+	}
+
+	return f.rotate()
+}
+
+// Reopen opens the path again and closes the previous file, which is needed
+// after it was moved by someone else. The previous file is kept when the
+// path can't be opened.
+func (f *RotatingFile) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return ErrWriterClosed
+	}
+
+	old := f.file
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	return old.Close()
+}
+
+// Close closes the file and waits for the rotated files to be compressed.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+
+	signal.Stop(f.signals)
+	close(f.signals)
+
+	err := f.file.Close()
+	f.file = nil
+	f.mill.Wait()
+
+	return err
+}
+
+func (f *RotatingFile) reopenOnSignal() {
+	for range f.signals {
+		if err := f.Reopen(); err != nil && !errors.Is(err, ErrWriterClosed) {
+			fmt.Fprintf(os.Stderr, "logging: reopening %s: %v\n", f.path, err)
+		}
+	}
+}
+
+func (f *RotatingFile) expired() bool {
+	return f.opts.MaxAge > 0 && time.Since(f.openedAt) >= f.opts.MaxAge
+}
+
+func (f *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file, f.size, f.openedAt = file, info.Size(), time.Now()
+
+	return nil
+}
+
+// rotate moves the file aside while it's still open, so that on any error
+// the entries keep being written to it.
+func (f *RotatingFile) rotate() error {
+	old := f.file
+
+	backup := f.backupName(time.Now())
+	if err := os.Rename(f.path, backup); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	// The entries were written, a failing close can't lose any.
+	_ = old.Close()
+
+	f.mill.Add(1)
+	go func() {
+		defer f.mill.Done()
+		f.millBackups()
+	}()
+
+	return nil
+}
+
+// backupName returns name-<timestamp>.ext for the file at path.
+func (f *RotatingFile) backupName(t time.Time) string {
+	prefix, ext := f.backupPrefix()
+
+	return prefix + t.UTC().Format(backupTimeFormat) + ext
+}
+
+func (f *RotatingFile) backupPrefix() (string, string) {
+	ext := filepath.Ext(f.path)
+
+	return strings.TrimSuffix(f.path, ext) + "-", ext
+}
+
+// millBackups removes the rotated files over MaxBackups and compresses the
+// others. It looks at all of them, so it doesn't depend on the order the
+// rotations are milled in.
+func (f *RotatingFile) millBackups() {
+	f.millMu.Lock()
+	defer f.millMu.Unlock()
+
+	backups, err := f.backups()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "logging: listing old logs of %s: %v\n", f.path, err)
+		return
+	}
+
+	if f.opts.MaxBackups > 0 {
+		for len(backups) > f.opts.MaxBackups {
+			if err := os.Remove(backups[0]); err != nil {
+				fmt.Fprintf(os.Stderr, "logging: removing %s: %v\n", backups[0], err)
+			}
+			backups = backups[1:]
+		}
+	}
+
+	if !f.opts.Compress {
+		return
+	}
+
+	for _, backup := range backups {
+		if strings.HasSuffix(backup, ".gz") {
+			continue
+		}
+
+		if err := compressFile(backup); err != nil {
+			fmt.Fprintf(os.Stderr, "logging: compressing %s: %v\n", backup, err)
+		}
+	}
+}
+
+// backups returns the rotated files, oldest first.
+func (f *RotatingFile) backups() ([]string, error) {
+	prefix, _ := f.backupPrefix()
+	matches, err := filepath.Glob(prefix + "*")
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []string
+	for _, m := range matches {
+		stamp := strings.TrimPrefix(m, prefix)
+		if len(stamp) < len(backupTimeFormat) {
+			continue
+		}
+
+		if _, err := time.Parse(backupTimeFormat, stamp[:len(backupTimeFormat)]); err == nil {
+			backups = append(backups, m)
+		}
+	}
+
+	// The timestamps sort in rotation order.
+	sort.Strings(backups)
+
+	return backups, nil
+}
+
+func compressFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+
+	if err := errors.Join(gz.Close(), dst.Close()); err != nil {
+		os.Remove(name + ".gz")
+		return err
+	}
+
+	return os.Remove(name)
+}
@@ -0,0 +1,78 @@
+package logging
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThatRotatingFileRotatesCompressesAndRetains(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bot.log")
+	f, err := NewRotatingFile(path, RotationOptions{MaxSize: 300, MaxBackups: 2, Compress: true})
+	assert.NoError(t, err)
+
+	for i := 0; i < 20; i++ {
+		// 100 bytes per entry, so three fit in a file.
+		line := fmt.Sprintf("page %02d", i)
+		_, err := f.Write([]byte(line + strings.Repeat(".", 100-len(line)-1) + "\n"))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, f.Close())
+
+	backups, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "bot-*.log.gz"))
+	assert.Len(t, backups, 2)
+
+	gz, _ := os.Open(backups[1])
+	defer gz.Close()
+	r, err := gzip.NewReader(gz)
+	assert.NoError(t, err)
+	b, _ := io.ReadAll(r)
+	assert.Equal(t, 300, len(b))
+	assert.Contains(t, string(b), "page 17")
+
+	current, _ := os.ReadFile(path)
+	assert.Contains(t, string(current), "page 19")
+	assert.LessOrEqual(t, len(current), 300)
+}
+
+func TestThatRotatingFileRotatesByAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bot.log")
+	f, err := NewRotatingFile(path, RotationOptions{MaxAge: time.Millisecond})
+	assert.NoError(t, err)
+
+	f.Write([]byte("first\n"))
+	time.Sleep(5 * time.Millisecond)
+	f.Write([]byte("second\n"))
+	assert.NoError(t, f.Close())
+
+	current, _ := os.ReadFile(path)
+	assert.Equal(t, "second\n", string(current))
+
+	backups, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "bot-*.log"))
+	assert.Len(t, backups, 1)
+}
+
+func TestThatRotatingFileKeepsItsFileWhenThePathCantBeOpened(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bot.log")
+	f, err := NewRotatingFile(path, RotationOptions{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.Rename(path, path+".moved"))
+	assert.NoError(t, os.Mkdir(path, 0o755))
+
+	assert.Error(t, f.Reopen())
+
+	_, err = f.Write([]byte("still written\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	moved, _ := os.ReadFile(path + ".moved")
+	assert.Equal(t, "still written\n", string(moved))
+}
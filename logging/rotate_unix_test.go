@@ -0,0 +1,59 @@
+//go:build !windows
+
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThatRotatingFileIsReopenedOnSIGHUP(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bot.log")
+	f, err := NewRotatingFile(path, RotationOptions{})
+	assert.NoError(t, err)
+	defer f.Close()
+
+	f.Write([]byte("before\n"))
+	assert.NoError(t, os.Rename(path, path+".1"))
+	assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+
+	assert.Eventually(t, func() bool {
+		f.Write([]byte("after\n"))
+		b, _ := os.ReadFile(path)
+		return strings.Contains(string(b), "after")
+	}, time.Second, 10*time.Millisecond)
+
+	moved, _ := os.ReadFile(path + ".1")
+	assert.True(t, strings.HasPrefix(string(moved), "before\n"))
+}
+
+func TestThatEntriesAreKeptWhenRotationFails(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	f, err := NewRotatingFile(filepath.Join(dir, "bot.log"), RotationOptions{MaxSize: 100})
+	assert.NoError(t, err)
+	defer f.Close()
+
+	line := []byte(strings.Repeat(".", 59) + "\n")
+	_, err = f.Write(line)
+	assert.NoError(t, err)
+
+	// The open file is moved along with its directory, whose path is now a
+	// file, so the rotation can't rename it.
+	assert.NoError(t, os.Rename(dir, dir+".moved"))
+	assert.NoError(t, os.WriteFile(dir, nil, 0o644))
+
+	for i := 0; i < 2; i++ {
+		_, err = f.Write(line)
+		assert.NoError(t, err)
+	}
+	assert.True(t, f.retryAt.After(time.Now()))
+
+	b, _ := os.ReadFile(filepath.Join(dir+".moved", "bot.log"))
+	assert.Equal(t, 3*len(line), len(b))
+}
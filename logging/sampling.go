@@ -0,0 +1,254 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const defaultSamplingWindow = time.Minute
+
+type (
+	// SamplingOptions configures NewSamplingHandler. Records are grouped by
+	// level, message template and caller, and counted over Window.
+	SamplingOptions struct {
+		// Window is the period the counters are kept for. Defaults to a
+		// minute.
+		Window time.Duration
+		// First records of a group are always logged in each window.
+		// Defaults to 1.
+		First int
+		// Thereafter logs every Nth record of a group once First is
+		// reached. Zero drops them all.
+		Thereafter int
+	}
+
+	samplingHandler struct {
+		slog.Handler
+		state *samplingState
+	}
+
+	samplingState struct {
+		opts      SamplingOptions
+		mu        sync.Mutex
+		groups    map[samplingKey]*samplingGroup
+		lastSweep time.Time
+		// timer writes the summaries of the windows that end without
+		// further records, armed while records are suppressed.
+		timer *time.Timer
+	}
+
+	samplingKey struct {
+		level    slog.Level
+		template string
+		pc       uintptr
+	}
+
+	samplingGroup struct {
+		start      time.Time
+		count      int
+		suppressed int
+		// The last suppressed record's context and handler, used to write
+		// the summary with the same enrichment.
+		ctx     context.Context
+		handler slog.Handler
+	}
+
+	messageTemplate struct{}
+)
+
+// WithSampling deduplicates and samples repeated records, see
+// NewSamplingHandler.
+func WithSampling(opts SamplingOptions) Option {
+	return func(o *options) {
+		o.sampling = &opts
+	}
+}
+
+// NewSamplingHandler wraps h so that only the first records of a group, and
+// then one every Thereafter, are logged per window. Errors always pass. When
+// a window ends with suppressed records a "N similar messages suppressed"
+// summary is written, even if the group is not logged again.
+func NewSamplingHandler(h slog.Handler, opts SamplingOptions) slog.Handler {
+	if opts.Window <= 0 {
+		opts.Window = defaultSamplingWindow
+	}
+	if opts.First < 1 {
+		opts.First = 1
+	}
+
+	return &samplingHandler{
+		Handler: h,
+		state: &samplingState{
+			opts:   opts,
+			groups: map[samplingKey]*samplingGroup{},
+		},
+	}
+}
+
+// withTemplate keeps the printf format of a message, so records built from
+// the same format are sampled together.
+func withTemplate(ctx context.Context, format string) context.Context {
+	return context.WithValue(ctx, messageTemplate{}, format)
+}
+
+func (s *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{Handler: s.Handler.WithAttrs(attrs), state: s.state}
+}
+
+func (s *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{Handler: s.Handler.WithGroup(name), state: s.state}
+}
+
+func (s *samplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelError {
+		return s.Handler.Handle(ctx, r)
+	}
+
+	template, ok := ctx.Value(messageTemplate{}).(string)
+	if !ok {
+		template = r.Message
+	}
+
+	now := r.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	summaries, pass := s.state.count(samplingKey{level: r.Level, template: template, pc: r.PC}, now, ctx, s.Handler)
+
+	for _, summary := range summaries {
+		_ = summary()
+	}
+
+	if !pass {
+		return nil
+	}
+
+	return s.Handler.Handle(ctx, r)
+}
+
+// count records one occurrence of key. It returns whether it must be logged
+// and the summaries of the windows that ended.
+func (st *samplingState) count(key samplingKey, now time.Time, ctx context.Context, h slog.Handler) ([]func() error, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var summaries []func() error
+
+	g, ok := st.groups[key]
+	if ok && now.Sub(g.start) >= st.opts.Window {
+		summaries = append(summaries, g.summary(key))
+		ok = false
+	}
+	if !ok {
+		g = &samplingGroup{start: now}
+		st.groups[key] = g
+	}
+
+	if now.Sub(st.lastSweep) >= st.opts.Window {
+		st.lastSweep = now
+		for k, other := range st.groups {
+			if k != key && now.Sub(other.start) >= st.opts.Window {
+				summaries = append(summaries, other.summary(k))
+				delete(st.groups, k)
+			}
+		}
+	}
+
+	g.count++
+
+	over := g.count - st.opts.First
+	pass := over <= 0 || (st.opts.Thereafter > 0 && over%st.opts.Thereafter == 0)
+	if !pass {
+		g.suppressed++
+		g.ctx = ctx
+		g.handler = h
+
+		if st.timer == nil {
+			st.timer = time.AfterFunc(g.start.Add(st.opts.Window).Sub(now), st.sweep)
+		}
+	}
+
+	return summaries, pass
+}
+
+// sweep writes the summaries of the ended windows, and waits for the next
+// one to end while records are still suppressed.
+func (st *samplingState) sweep() {
+	st.mu.Lock()
+
+	now := time.Now()
+	st.timer = nil
+
+	var (
+		summaries []func() error
+		next      time.Time
+	)
+	for k, g := range st.groups {
+		end := g.start.Add(st.opts.Window)
+		if !now.Before(end) {
+			summaries = append(summaries, g.summary(k))
+			delete(st.groups, k)
+			continue
+		}
+
+		if g.suppressed != 0 && (next.IsZero() || end.Before(next)) {
+			next = end
+		}
+	}
+
+	if !next.IsZero() {
+		st.timer = time.AfterFunc(next.Sub(now), st.sweep)
+	}
+
+	st.mu.Unlock()
+
+	for _, summary := range summaries {
+		_ = summary()
+	}
+}
+
+// flush writes the summaries of every group, keeping their windows.
+func (st *samplingState) flush() {
+	st.mu.Lock()
+
+	if st.timer != nil {
+		st.timer.Stop()
+		st.timer = nil
+	}
+
+	var summaries []func() error
+	for k, g := range st.groups {
+		summaries = append(summaries, g.summary(k))
+		g.suppressed = 0
+	}
+
+	st.mu.Unlock()
+
+	for _, summary := range summaries {
+		_ = summary()
+	}
+}
+
+// summary returns the write of the group summary, a no-op when nothing was
+// suppressed.
+func (g *samplingGroup) summary(key samplingKey) func() error {
+	if g.suppressed == 0 {
+		return func() error { return nil }
+	}
+
+	r := slog.NewRecord(time.Now(), key.level, fmt.Sprintf("%d similar messages suppressed", g.suppressed), key.pc)
+	r.AddAttrs(
+		slog.Int("suppressed", g.suppressed),
+		slog.String("template", key.template),
+	)
+
+	ctx, h := g.ctx, g.handler
+
+	return func() error {
+		return h.Handle(ctx, r)
+	}
+}
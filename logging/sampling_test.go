@@ -0,0 +1,89 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThatRepeatedRecordsAreSampled(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b, WithSampling(SamplingOptions{
+		Window:     time.Hour,
+		First:      2,
+		Thereafter: 5,
+	})))
+
+	for i := 0; i < 12; i++ {
+		Warnf(ctx, "retrying account %d", i)
+	}
+
+	// The first two, then the 7th and the 12th.
+	assert.Equal(t, 4, strings.Count(b.String(), "retrying account"))
+}
+
+func TestThatErrorsAndDifferentCallersAreNotSampled(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b, WithSampling(SamplingOptions{Window: time.Hour, First: 1})))
+
+	for i := 0; i < 3; i++ {
+		Errorf(ctx, "failed")
+		Infof(ctx, "first caller")
+		Infof(ctx, "second caller")
+	}
+
+	assert.Equal(t, 3, strings.Count(b.String(), `"message":"failed"`))
+	assert.Equal(t, 1, strings.Count(b.String(), `"message":"first caller"`))
+	assert.Equal(t, 1, strings.Count(b.String(), `"message":"second caller"`))
+}
+
+func TestThatSuppressedRecordsAreSummarized(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b, WithSampling(SamplingOptions{
+		Window: 20 * time.Millisecond,
+		First:  1,
+	})))
+
+	log := func() { Info(ctx, "polling", "attempt", 1) }
+	for i := 0; i < 5; i++ {
+		log()
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	log()
+
+	assert.Contains(t, b.String(), `"message":"4 similar messages suppressed"`)
+	assert.Contains(t, b.String(), `"template":"polling"`)
+	assert.Equal(t, 2, strings.Count(b.String(), `"message":"polling"`))
+}
+
+func TestThatSummariesAreWrittenWhenTheGroupGoesQuiet(t *testing.T) {
+	b := &syncBuffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b, WithSampling(SamplingOptions{Window: 20 * time.Millisecond})))
+
+	for i := 0; i < 3; i++ {
+		Info(ctx, "polling")
+	}
+
+	assert.Eventually(t, func() bool {
+		return strings.Contains(b.String(), `"message":"2 similar messages suppressed"`)
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestThatFlushWritesThePendingSummaries(t *testing.T) {
+	b := &bytes.Buffer{}
+	logger := NewLoggerWithWriter(b, WithSampling(SamplingOptions{Window: time.Hour}))
+	ctx := SetLogger(context.Background(), logger)
+
+	for i := 0; i < 3; i++ {
+		Infof(ctx, "polling %d", i)
+	}
+	logger.Flush()
+
+	assert.Equal(t, 1, strings.Count(b.String(), "polling 0"))
+	assert.Contains(t, b.String(), `"message":"2 similar messages suppressed"`)
+}
@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithSpanEvents adds the entries at or above level, and the reported
+// errors, as events of the active span. Reported errors also set the span
+// status to Error.
+func WithSpanEvents(level slog.Leveler) Option {
+	return func(o *options) {
+		o.spanEvents = level
+	}
+}
+
+// addSpanEvent mirrors the entry on the recording span of ctx, if any.
+func addSpanEvent(ctx context.Context, level slog.Level, msg string, attrs []slog.Attr) {
+	span := spanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+
+	kvs := []attribute.KeyValue{
+		attribute.String("message", msg),
+		attribute.String("severity", severity(level)),
+	}
+	kvs = appendSpanAttributes(kvs, "", attrs)
+
+	span.AddEvent("log", trace.WithAttributes(kvs...))
+}
+
+// spanFromContext returns the span of ctx, or of its traceContext value like
+// getSpanContext.
+func spanFromContext(ctx context.Context) trace.Span {
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		return span
+	}
+
+	if traceCtx, ok := ctx.Value("traceContext").(context.Context); ok {
+		return trace.SpanFromContext(traceCtx)
+	}
+
+	return span
+}
+
+// appendSpanAttributes flattens the attributes, prefixing the keys with the
+// groups they are in.
+func appendSpanAttributes(kvs []attribute.KeyValue, prefix string, attrs []slog.Attr) []attribute.KeyValue {
+	for _, a := range attrs {
+		key := prefix + a.Key
+		v := a.Value.Resolve()
+
+		switch v.Kind() {
+		case slog.KindGroup:
+			kvs = appendSpanAttributes(kvs, key+".", v.Group())
+		case slog.KindString:
+			kvs = append(kvs, attribute.String(key, v.String()))
+		case slog.KindInt64:
+			kvs = append(kvs, attribute.Int64(key, v.Int64()))
+		case slog.KindFloat64:
+			kvs = append(kvs, attribute.Float64(key, v.Float64()))
+		case slog.KindBool:
+			kvs = append(kvs, attribute.Bool(key, v.Bool()))
+		default:
+			kvs = append(kvs, attribute.String(key, attrString(v)))
+		}
+	}
+
+	return kvs
+}
@@ -0,0 +1,89 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestThatErrorsAreMirroredOnTheSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "sync")
+	ctx = SetLogger(ctx, NewLoggerWithWriter(&bytes.Buffer{}, WithSpanEvents(slog.LevelWarn)))
+
+	Infof(ctx, "starting")
+	Warn(ctx, "slow page", "page", 3)
+	Reportf(ctx, "login failed: %v", errors.New("timeout"))
+	span.End()
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+
+	events := spans[0].Events()
+	assert.Len(t, events, 2)
+	assert.Equal(t, "log", events[0].Name)
+	assert.Contains(t, events[0].Attributes, attribute.String("message", "slow page"))
+	assert.Contains(t, events[0].Attributes, attribute.Int64("page", 3))
+	assert.Contains(t, events[1].Attributes, attribute.String("severity", "ERROR"))
+
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "login failed: timeout", spans[0].Status().Description)
+}
+
+func TestThatErrorsDontChangeTheSpanStatus(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "sync")
+	ctx = SetLogger(ctx, NewLoggerWithWriter(&bytes.Buffer{}, WithSpanEvents(slog.LevelError)))
+
+	Errorf(ctx, "retrying")
+	span.End()
+
+	spans := recorder.Ended()
+	assert.Len(t, spans[0].Events(), 1)
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+}
+
+func TestThatReportsLeaveTheSpanAloneWithoutSpanEvents(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "sync")
+	ctx = SetLogger(ctx, NewLoggerWithWriter(&bytes.Buffer{}))
+
+	Reportf(ctx, "login failed")
+	span.End()
+
+	spans := recorder.Ended()
+	assert.Empty(t, spans[0].Events())
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+}
+
+func TestThatRecoveredPanicsAreRecordedOnce(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "bot")
+	ctx = SetLogger(ctx, NewLoggerWithWriter(&bytes.Buffer{}, WithSpanEvents(slog.LevelWarn)))
+
+	func() {
+		defer Recover(ctx)
+		panicking()
+	}()
+	span.End()
+
+	events := recorder.Ended()[0].Events()
+	assert.Len(t, events, 1)
+	assert.Equal(t, "exception", events[0].Name)
+}
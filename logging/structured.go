@@ -0,0 +1,116 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/propertechnologies/monitor/context_util"
+)
+
+type (
+	// StructuredLogger is implemented by loggers that can emit attributes as
+	// fields instead of formatting them into the message.
+	StructuredLogger interface {
+		Log(ctx context.Context, level slog.Level, msg string, args ...interface{})
+		With(args ...interface{}) Logger
+	}
+)
+
+// Debug logs msg with the given key/value pairs or slog.Attr as fields.
+func Debug(ctx context.Context, msg string, args ...interface{}) {
+	logAttrs(ctx, slog.LevelDebug, msg, args...)
+}
+
+// Info logs msg with the given key/value pairs or slog.Attr as fields.
+func Info(ctx context.Context, msg string, args ...interface{}) {
+	logAttrs(ctx, slog.LevelInfo, msg, args...)
+}
+
+// Warn logs msg with the given key/value pairs or slog.Attr as fields.
+func Warn(ctx context.Context, msg string, args ...interface{}) {
+	logAttrs(ctx, slog.LevelWarn, msg, args...)
+}
+
+// Error logs msg with the given key/value pairs or slog.Attr as fields.
+func Error(ctx context.Context, msg string, args ...interface{}) {
+	logAttrs(ctx, slog.LevelError, msg, args...)
+}
+
+// With returns a context whose logger adds args to every entry, e.g.
+//
+//	ctx = logging.With(ctx, "account_id", id)
+func With(ctx context.Context, args ...interface{}) context.Context {
+	log := GetLoggerOrDefault(ctx, &DefaultLogger{})
+
+	s, ok := log.(StructuredLogger)
+	if !ok {
+		return ctx
+	}
+
+	return SetLogger(ctx, s.With(args...))
+}
+
+func logAttrs(ctx context.Context, level slog.Level, msg string, args ...interface{}) {
+	log := GetLoggerOrDefault(ctx, &DefaultLogger{})
+
+	if s, ok := log.(StructuredLogger); ok {
+		s.Log(ctx, level, msg, args...)
+		return
+	}
+
+	// Loggers without attribute support get them appended to the message.
+	msg = formatAttrs(msg, args)
+
+	switch {
+	case level >= slog.LevelError:
+		log.Errorf(ctx, "%s", msg)
+	case level >= slog.LevelWarn:
+		log.Warnf(ctx, "%s", msg)
+	case level >= slog.LevelInfo:
+		log.Infof(ctx, "%s", msg)
+	default:
+		log.Debugf(ctx, "%s", msg)
+	}
+}
+
+func (g *GCPLoggerWrapper) Log(ctx context.Context, level slog.Level, msg string, args ...interface{}) {
+	g.log(ctx, level, msg, args)
+}
+
+func (g *GCPLoggerWrapper) With(args ...interface{}) Logger {
+	return &GCPLoggerWrapper{logger: g.logger.With(args...), callers: g.callers, sampling: g.sampling}
+}
+
+func (l *DefaultLogger) Log(ctx context.Context, level slog.Level, msg string, args ...interface{}) {
+	if level < slog.LevelInfo && !context_util.IsDebugOn(ctx) {
+		return
+	}
+
+	fmt.Println(formatAttrs(msg, append(l.args, args...)))
+}
+
+func (l *DefaultLogger) With(args ...interface{}) Logger {
+	return &DefaultLogger{args: append(append([]interface{}{}, l.args...), args...)}
+}
+
+// formatAttrs renders args as key=value pairs after msg.
+func formatAttrs(msg string, args []interface{}) string {
+	if len(args) == 0 {
+		return msg
+	}
+
+	r := slog.NewRecord(time.Time{}, slog.LevelInfo, msg, 0)
+	r.Add(args...)
+
+	var b strings.Builder
+	b.WriteString(msg)
+	r.Attrs(func(a slog.Attr) bool {
+		fmt.Fprintf(&b, " %s=%v", a.Key, a.Value)
+		return true
+	})
+
+	return b.String()
+}
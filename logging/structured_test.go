@@ -0,0 +1,87 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/propertechnologies/monitor/context_util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThatStructuredAttributesAreEmittedAsFields(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b))
+	ctx = context_util.SetServiceName(ctx, "ledgerlord")
+
+	Info(ctx, "account synced", "account_id", "acc-1", "level", "high")
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &entry))
+	assert.Equal(t, "account synced", entry["message"])
+	assert.Equal(t, "INFO", entry["severity"])
+	assert.Equal(t, "acc-1", entry["account_id"])
+	assert.Equal(t, "high", entry["level"])
+	assert.Equal(t, "ledgerlord", entry["app"])
+}
+
+func TestThatWithStoresADerivedLoggerInContext(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := SetLogger(context.Background(), NewLoggerWithWriter(b))
+
+	ctx = With(ctx, "account_id", "acc-1")
+	ctx = With(ctx, "bank", "chase")
+	Infof(ctx, "balance is %d", 10)
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &entry))
+	assert.Equal(t, "balance is 10", entry["message"])
+	assert.Equal(t, "acc-1", entry["account_id"])
+	assert.Equal(t, "chase", entry["bank"])
+}
+
+func TestThatGroupsDoNotNestTheCloudLoggingFields(t *testing.T) {
+	b := &bytes.Buffer{}
+	logger := NewLoggerWithWriter(b)
+	logger.logger = logger.logger.WithGroup("bot")
+	ctx := SetLogger(context.Background(), logger)
+
+	Warn(ctx, "retrying", "attempt", 2)
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &entry))
+	assert.Equal(t, "WARNING", entry["severity"])
+	assert.Contains(t, entry, "flow-id")
+	assert.Equal(t, map[string]interface{}{"attempt": float64(2)}, entry["bot"])
+}
+
+func TestThatAttributesAreAppendedForPrintfOnlyLoggers(t *testing.T) {
+	l := &printfLogger{}
+	ctx := SetLogger(context.Background(), l)
+
+	Error(ctx, "failed", "account_id", "acc-1")
+
+	assert.Equal(t, "failed account_id=acc-1", l.last)
+}
+
+type printfLogger struct {
+	last string
+}
+
+func (p *printfLogger) Debugf(ctx context.Context, format string, args ...interface{}) {
+	p.last = fmt.Sprintf(format, args...)
+}
+
+func (p *printfLogger) Infof(ctx context.Context, format string, args ...interface{}) {
+	p.last = fmt.Sprintf(format, args...)
+}
+
+func (p *printfLogger) Errorf(ctx context.Context, format string, args ...interface{}) {
+	p.last = fmt.Sprintf(format, args...)
+}
+
+func (p *printfLogger) Warnf(ctx context.Context, format string, args ...interface{}) {
+	p.last = fmt.Sprintf(format, args...)
+}
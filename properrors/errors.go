@@ -48,6 +48,12 @@ func (p *Error) Wrap(e error) *Error {
 	return p
 }
 
+// Unwrap returns the wrapped error, so errors.Is and errors.As can walk
+// through it.
+func (p *Error) Unwrap() error {
+	return p.WError
+}
+
 func (p *Error) WithSubType(code string, errMsg string) *Error {
 	t := New(p.ID, p.Err)
 	t.ID = fmt.Sprintf("%s-%s", p.ID, code)
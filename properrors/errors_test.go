@@ -46,3 +46,12 @@ func TestThatIsReturnsTrueIfErrorIsTheSame(t *testing.T) {
 		t.Errorf("Is() should return true")
 	}
 }
+
+func TestThatWrappedErrorIsReachable(t *testing.T) {
+	cause := errors.New("foo")
+	err := New("9999", "Test error").Wrap(cause)
+
+	if !errors.Is(err, cause) {
+		t.Errorf("wrapped error should be reachable")
+	}
+}